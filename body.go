@@ -0,0 +1,481 @@
+package openapirouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// RegisterBodyDecoder sets the openapi3filter.BodyDecoder for the media type of request bodies, e.g. text/csv, or
+// replaces a built-in decoder. The decoder is used to validate request and response bodies with the media type against
+// their schema and to bind request bodies with BindBody or typed handler functions. It has to return the body as
+// primitive, []interface{} or map[string]interface{}. A decoder registered for application/json, application/xml or
+// application/yaml is also used for the media types with the corresponding structured syntax suffix, unless they have
+// their own decoder. Media types without a decoder of the Router are decoded by the decoders registered in the
+// openapi3filter package. The decoder must be registered before the Router serves requests.
+func (router *Router) RegisterBodyDecoder(mediaType string, decoder openapi3filter.BodyDecoder) {
+	router.decoders.register(mediaType, decoder)
+}
+
+// decoderRegistry contains the openapi3filter.BodyDecoder for each media type the Router decodes itself instead of
+// the openapi3filter package.
+type decoderRegistry struct {
+	decoders map[string]openapi3filter.BodyDecoder
+}
+
+// newDecoderRegistry creates the decoderRegistry with the built-in decoders for XML and multipart/form-data.
+func newDecoderRegistry() *decoderRegistry {
+	return &decoderRegistry{decoders: map[string]openapi3filter.BodyDecoder{
+		"application/xml":     XMLBodyDecoder,
+		"text/xml":            XMLBodyDecoder,
+		"multipart/form-data": MultipartBodyDecoder,
+	}}
+}
+
+// register sets the decoder for the media type. An existing decoder is replaced.
+func (registry *decoderRegistry) register(mediaType string, decoder openapi3filter.BodyDecoder) {
+	registry.decoders[normalizeMediaType(mediaType)] = decoder
+}
+
+// lookup returns the decoder for the media type. A decoder registered with the Router takes precedence over the one
+// of the openapi3filter package. Without a decoder for the media type itself, the decoder of its structured syntax
+// suffix is used, e.g. application/json for application/vnd.clients+json. The second return value is true, if the
+// decoder is not the one openapi3filter uses for the media type, so the Router has to decode such bodies itself.
+func (registry *decoderRegistry) lookup(mediaType string) (openapi3filter.BodyDecoder, bool) {
+	mediaType = normalizeMediaType(mediaType)
+	if decoder, ok := registry.decoders[mediaType]; ok {
+		return decoder, true
+	}
+	if decoder := openapi3filter.RegisteredBodyDecoder(mediaType); decoder != nil {
+		return decoder, false
+	}
+	if index := strings.LastIndex(mediaType, "+"); index >= 0 {
+		suffixType := "application/" + mediaType[index+1:]
+		if decoder, ok := registry.decoders[suffixType]; ok {
+			return decoder, true
+		}
+		if decoder := openapi3filter.RegisteredBodyDecoder(suffixType); decoder != nil {
+			return decoder, true
+		}
+	}
+	return nil, false
+}
+
+// decodes returns the documented content of the request body, if the Router decodes the body of the request itself,
+// because openapi3filter has no or another decoder for its media type.
+func (registry *decoderRegistry) decodes(request *http.Request, operation *openapi3.Operation) *openapi3.MediaType {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return nil
+	}
+	contentType := request.Header.Get("Content-Type")
+	content := operation.RequestBody.Value.Content.Get(contentType)
+	if content == nil || content.Schema == nil || content.Schema.Value == nil {
+		return nil
+	}
+	if decoder, own := registry.lookup(contentType); decoder == nil || !own {
+		return nil
+	}
+	return content
+}
+
+// validateRequestBody validates the body of a request, which the Router decodes itself, against the schema of the
+// content like openapi3filter.ValidateRequestBody does. Default values of the schema are not set. The body is kept
+// for the handler function.
+func (registry *decoderRegistry) validateRequestBody(input *openapi3filter.RequestValidationInput,
+	content *openapi3.MediaType) error {
+	request := input.Request
+	requestBody := input.Route.Operation.RequestBody.Value
+	var data []byte
+	if request.Body != nil && request.Body != http.NoBody {
+		var err error
+		data, err = io.ReadAll(request.Body)
+		_ = request.Body.Close()
+		if err != nil {
+			return &openapi3filter.RequestError{Input: input, RequestBody: requestBody, Reason: "reading failed",
+				Err: err}
+		}
+		request.Body = io.NopCloser(bytes.NewReader(data))
+		request.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+	if len(data) == 0 {
+		if requestBody.Required {
+			return &openapi3filter.RequestError{Input: input, RequestBody: requestBody,
+				Err: openapi3filter.ErrInvalidRequired}
+		}
+		return nil
+	}
+	decoder, _ := registry.lookup(request.Header.Get("Content-Type"))
+	value, err := decoder(bytes.NewReader(data), request.Header, content.Schema, func(name string) *openapi3.Encoding {
+		return content.Encoding[name]
+	})
+	if err != nil {
+		return &openapi3filter.RequestError{Input: input, RequestBody: requestBody,
+			Reason: "failed to decode request body", Err: err}
+	}
+	opts := []openapi3.SchemaValidationOption{openapi3.VisitAsRequest()}
+	if input.Options != nil && input.Options.MultiError {
+		opts = append(opts, openapi3.MultiErrors())
+	}
+	if input.Options != nil && input.Options.ExcludeReadOnlyValidations {
+		opts = append(opts, openapi3.DisableReadOnlyValidation())
+	}
+	if err := content.Schema.Value.VisitJSON(value, opts...); err != nil {
+		return &openapi3filter.RequestError{Input: input, RequestBody: requestBody, Reason: "doesn't match schema",
+			Err: err}
+	}
+	return nil
+}
+
+// withBodyError adds the error of the validation of a request body, which the Router decoded itself, to the error
+// returned by openapi3filter.ValidateRequest.
+func withBodyError(err error, bodyErr error) error {
+	if bodyErr == nil {
+		return err
+	}
+	if multiErr, ok := err.(openapi3.MultiError); ok {
+		return append(multiErr, bodyErr)
+	}
+	if err == nil {
+		return bodyErr
+	}
+	return err
+}
+
+// decodersOf returns the decoderRegistry of the Router, which handles the request. Requests not handled by a Router
+// are decoded with the built-in decoders.
+func decodersOf(request *http.Request) *decoderRegistry {
+	if decoders, ok := request.Context().Value(decodersKey).(*decoderRegistry); ok {
+		return decoders
+	}
+	return newDecoderRegistry()
+}
+
+// BindBody fills the value pointed to by target with the body of a request handled by a Router. The body is decoded
+// with the openapi3filter.BodyDecoder of its Content-Type registered with the Router, so the same struct can be bound
+// from JSON, XML, YAML, form or multipart bodies. Struct fields are matched by their json tag or, like encoding/json
+// does, by their name ignoring the case. For multipart/form-data bodies, only the values of the Upload are bound, the
+// files are available with UploadOf. An empty body leaves the value unchanged.
+func BindBody(request *http.Request, target interface{}) error {
+	contentType := request.Header.Get("Content-Type")
+	if upload := UploadOf(request); upload != nil {
+		values, err := upload.decodeValues(requestBodyContent(request, contentType).Schema.Value)
+		if err != nil {
+			return err
+		}
+		return bindValue(values, target)
+	}
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil
+	}
+	mediaType := normalizeMediaType(contentType)
+	if mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		err := json.NewDecoder(request.Body).Decode(target)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+	decoder, _ := decodersOf(request).lookup(mediaType)
+	if decoder == nil {
+		return fmt.Errorf("no body decoder for media type %s", mediaType)
+	}
+	var schema *openapi3.SchemaRef
+	encoding := func(string) *openapi3.Encoding { return nil }
+	if content := requestBodyContent(request, contentType); content != nil {
+		schema = content.Schema
+		encoding = func(name string) *openapi3.Encoding { return content.Encoding[name] }
+	}
+	if schema == nil {
+		schema = openapi3.NewObjectSchema().NewRef()
+	}
+	value, err := decoder(request.Body, request.Header, schema, encoding)
+	if err != nil {
+		return err
+	}
+	return bindValue(value, target)
+}
+
+// bindValue fills the value pointed to by target with a decoded body by converting it to JSON.
+func bindValue(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// requestBodyContent returns the media type of the request body documented for the operation of the request.
+func requestBodyContent(request *http.Request, contentType string) *openapi3.MediaType {
+	route, ok := RouteFromContext(request.Context())
+	if !ok || route.Operation.RequestBody == nil || route.Operation.RequestBody.Value == nil {
+		return nil
+	}
+	return route.Operation.RequestBody.Value.Content.Get(contentType)
+}
+
+// XMLBodyDecoder is an openapi3filter.BodyDecoder for application/xml, text/xml or vendor media types with the suffix
+// +xml, which decodes the body according to its schema. It is registered with every Router. The properties of an object
+// are read from the child elements or, if they are marked as attribute, from the attributes with the name of the
+// property or its xml name. Arrays are read from repeated elements, which are enclosed by an element with the name of
+// the property, if the array is marked as wrapped. The name of the root element is not checked.
+func XMLBodyDecoder(body io.Reader, _ http.Header, schema *openapi3.SchemaRef,
+	_ openapi3filter.EncodingFn) (interface{}, error) {
+	root, err := parseXML(body)
+	if err != nil {
+		return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+	}
+	value, err := root.decode(schema)
+	if err != nil {
+		return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+	}
+	return value, nil
+}
+
+// xmlNode is an element of an XML document.
+type xmlNode struct {
+	name       string
+	attributes []xml.Attr
+	children   []*xmlNode
+	text       strings.Builder
+}
+
+// parseXML reads the root element of an XML document.
+func parseXML(reader io.Reader) (*xmlNode, error) {
+	decoder := xml.NewDecoder(reader)
+	var stack []*xmlNode
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: element.Name.Local, attributes: element.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return node, nil
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(element)
+			}
+		}
+	}
+}
+
+// decode converts the element to a value of the schema.
+func (node *xmlNode) decode(schema *openapi3.SchemaRef) (interface{}, error) {
+	if schema == nil || schema.Value == nil {
+		return node.decodeUntyped(), nil
+	}
+	switch schema.Value.Type {
+	case openapi3.TypeObject, "":
+		if len(schema.Value.Properties) == 0 {
+			return node.decodeUntyped(), nil
+		}
+		return node.decodeObject(schema.Value)
+	case openapi3.TypeArray:
+		return decodeXMLArray(node.children, schema.Value.Items)
+	default:
+		return parsePrimitive(strings.TrimSpace(node.text.String()), schema.Value)
+	}
+}
+
+// decodeObject converts the element to a map with the properties of the schema.
+func (node *xmlNode) decodeObject(schema *openapi3.Schema) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for name, property := range schema.Properties {
+		if property.Value == nil {
+			continue
+		}
+		xmlName, xmlInfo := name, property.Value.XML
+		if xmlInfo != nil && xmlInfo.Name != "" {
+			xmlName = xmlInfo.Name
+		}
+		if xmlInfo != nil && xmlInfo.Attribute {
+			for _, attribute := range node.attributes {
+				if attribute.Name.Local == xmlName {
+					value, err := parsePrimitive(attribute.Value, property.Value)
+					if err != nil {
+						return nil, fmt.Errorf("attribute %s: %w", xmlName, err)
+					}
+					result[name] = value
+				}
+			}
+			continue
+		}
+		children := node.childrenNamed(xmlName)
+		if property.Value.Type == openapi3.TypeArray {
+			if xmlInfo != nil && xmlInfo.Wrapped {
+				if len(children) == 0 {
+					continue
+				}
+				children = children[0].children
+			}
+			if len(children) == 0 {
+				continue
+			}
+			value, err := decodeXMLArray(children, property.Value.Items)
+			if err != nil {
+				return nil, fmt.Errorf("element %s: %w", xmlName, err)
+			}
+			result[name] = value
+			continue
+		}
+		if len(children) == 0 {
+			continue
+		}
+		value, err := children[0].decode(property)
+		if err != nil {
+			return nil, fmt.Errorf("element %s: %w", xmlName, err)
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+// decodeXMLArray converts the elements to the items of an array.
+func decodeXMLArray(nodes []*xmlNode, items *openapi3.SchemaRef) ([]interface{}, error) {
+	result := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		value, err := node.decode(items)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// decodeUntyped converts an element without schema to its text, or to a map of its child elements, if it has any.
+func (node *xmlNode) decodeUntyped() interface{} {
+	if len(node.children) == 0 {
+		return strings.TrimSpace(node.text.String())
+	}
+	result := make(map[string]interface{})
+	for _, child := range node.children {
+		result[child.name] = child.decodeUntyped()
+	}
+	return result
+}
+
+// childrenNamed returns the child elements with the local name.
+func (node *xmlNode) childrenNamed(name string) []*xmlNode {
+	var children []*xmlNode
+	for _, child := range node.children {
+		if child.name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// MultipartBodyDecoder is an openapi3filter.BodyDecoder for multipart/form-data, which is registered with every Router
+// instead of the one of the openapi3filter package. Documented multipart/form-data request bodies are read as Upload by
+// the Router, so the decoder is used for other bodies, e.g. multipart responses. Each part is decoded according to the
+// schema of the property with the name of the part, so integers, numbers and booleans are typed correctly. Parts of
+// array properties are collected to an array. A part with a Content-Type other than text/plain or
+// application/octet-stream is decoded with the body decoder of its media type, e.g. a JSON object. Files are decoded as
+// string.
+func MultipartBodyDecoder(body io.Reader, header http.Header, schema *openapi3.SchemaRef,
+	_ openapi3filter.EncodingFn) (interface{}, error) {
+	_, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+	}
+	reader := multipart.NewReader(body, params["boundary"])
+	result := make(map[string]interface{})
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+		}
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+		property := propertySchema(schema, name)
+		isArray := property != nil && property.Type == openapi3.TypeArray
+		if isArray {
+			items := property.Items
+			property = nil
+			if items != nil {
+				property = items.Value
+			}
+		}
+		value, err := decodePart(part, property)
+		if err != nil {
+			return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat,
+				Reason: fmt.Sprintf("part %s", name), Cause: err}
+		}
+		if !isArray {
+			result[name] = value
+			continue
+		}
+		values, _ := result[name].([]interface{})
+		result[name] = append(values, value)
+	}
+}
+
+// propertySchema returns the schema of the property of an object schema. If the object has no such property, the
+// schema of its additional properties is returned, which may be nil.
+func propertySchema(schema *openapi3.SchemaRef, name string) *openapi3.Schema {
+	if schema == nil || schema.Value == nil {
+		return nil
+	}
+	if property := schema.Value.Properties[name]; property != nil {
+		return property.Value
+	}
+	if schema.Value.AdditionalProperties.Schema != nil {
+		return schema.Value.AdditionalProperties.Schema.Value
+	}
+	return nil
+}
+
+// decodePart decodes a part of a multipart body according to the schema, which may be nil.
+func decodePart(part *multipart.Part, schema *openapi3.Schema) (interface{}, error) {
+	mediaType := normalizeMediaType(part.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "text/plain" && mediaType != "application/octet-stream" {
+		if decoder := openapi3filter.RegisteredBodyDecoder(mediaType); decoder != nil {
+			var schemaRef *openapi3.SchemaRef
+			if schema != nil {
+				schemaRef = schema.NewRef()
+			} else {
+				schemaRef = openapi3.NewSchema().NewRef()
+			}
+			return decoder(part, http.Header(part.Header), schemaRef, nil)
+		}
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		return nil, err
+	}
+	if schema != nil && schema.Type != openapi3.TypeObject && schema.Type != openapi3.TypeArray {
+		return parsePrimitive(string(data), schema)
+	}
+	return string(data), nil
+}
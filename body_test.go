@@ -0,0 +1,245 @@
+package openapirouter
+
+import (
+	"bytes"
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const bodyTestSpec = `
+openapi: 3.0.3
+info:
+  title: Body-API
+  version: 1.0.0
+paths:
+  /clients:
+    post:
+      operationId: createClient
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Client'
+          application/xml:
+            schema:
+              $ref: '#/components/schemas/Client'
+          application/vnd.clients+xml:
+            schema:
+              $ref: '#/components/schemas/Client'
+          application/vnd.clients+json:
+            schema:
+              $ref: '#/components/schemas/Client'
+          application/x-www-form-urlencoded:
+            schema:
+              $ref: '#/components/schemas/Client'
+          multipart/form-data:
+            schema:
+              $ref: '#/components/schemas/Client'
+          text/x-client:
+            schema:
+              $ref: '#/components/schemas/Client'
+      responses:
+        '204':
+          description: created
+components:
+  schemas:
+    Client:
+      type: object
+      required: [name]
+      properties:
+        id:
+          type: integer
+          xml:
+            attribute: true
+        name:
+          type: string
+        tags:
+          type: array
+          items:
+            type: string
+            xml:
+              name: tag
+          xml:
+            wrapped: true
+`
+
+type bodyTestClient struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func getBodyRouter(opts ...Option) (*Router, *bodyTestClient) {
+	router, err := NewRouterFromData([]byte(bodyTestSpec), opts...)
+	if err != nil {
+		panic(err)
+	}
+	bound := &bodyTestClient{}
+	Handle(router, "createClient", func(_ context.Context, client bodyTestClient, _ Params) (*Response, error) {
+		*bound = client
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	return router, bound
+}
+
+func multipartBody() (string, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("id", "42")
+	_ = writer.WriteField("name", "test")
+	_ = writer.WriteField("tags", "a")
+	_ = writer.WriteField("tags", "b")
+	_ = writer.Close()
+	return writer.FormDataContentType(), body.String()
+}
+
+func TestBindBody_ShouldDecodeMediaTypes(t *testing.T) {
+	multipartType, multipartContent := multipartBody()
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"json", "application/json", `{"id":42,"name":"test","tags":["a","b"]}`},
+		{"xml", "application/xml", `<client id="42"><name>test</name><tags><tag>a</tag><tag>b</tag></tags></client>`},
+		{"vendor xml", "application/vnd.clients+xml",
+			`<?xml version="1.0" encoding="UTF-8"?><client id="42"><name>test</name><tags><tag>a</tag><tag>b</tag></tags></client>`},
+		{"vendor json", "application/vnd.clients+json", `{"id":42,"name":"test","tags":["a","b"]}`},
+		{"form", "application/x-www-form-urlencoded", "id=42&name=test&tags=a&tags=b"},
+		{"multipart", multipartType, multipartContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, bound := getBodyRouter()
+			request := httptest.NewRequest(http.MethodPost, "/clients", strings.NewReader(test.body))
+			request.Header.Set("Content-Type", test.contentType)
+			recorder := httptest.NewRecorder()
+
+			// when
+			router.ServeHTTP(recorder, request)
+
+			// then
+			assert.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+			assert.Equal(t, bodyTestClient{ID: 42, Name: "test", Tags: []string{"a", "b"}}, *bound)
+		})
+	}
+}
+
+func TestBindBody_ShouldValidateXML(t *testing.T) {
+	// given
+	router, _ := getBodyRouter()
+	request := httptest.NewRequest(http.MethodPost, "/clients",
+		strings.NewReader(`<client id="42"><tags><tag>a</tag></tags></client>`))
+	request.Header.Set("Content-Type", "application/xml")
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, request)
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "name")
+}
+
+func TestBindBody_InvalidXML(t *testing.T) {
+	// given
+	router, _ := getBodyRouter()
+	request := httptest.NewRequest(http.MethodPost, "/clients", strings.NewReader(`<client id="x"><name>test`))
+	request.Header.Set("Content-Type", "application/xml")
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, request)
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestRegisterBodyDecoder_ShouldValidateAndBind(t *testing.T) {
+	// given
+	router, bound := getBodyRouter()
+	router.RegisterBodyDecoder("text/x-client", func(body io.Reader, _ http.Header, _ *openapi3.SchemaRef,
+		_ openapi3filter.EncodingFn) (interface{}, error) {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Split(string(data), ";")
+		return map[string]interface{}{"id": float64(len(fields)), "name": fields[0]}, nil
+	})
+	request := httptest.NewRequest(http.MethodPost, "/clients", strings.NewReader("test;a;b"))
+	request.Header.Set("Content-Type", "text/x-client")
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, request)
+
+	// then
+	assert.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+	assert.Equal(t, bodyTestClient{ID: 3, Name: "test"}, *bound)
+	assert.Nil(t, openapi3filter.RegisteredBodyDecoder("text/x-client"))
+}
+
+func TestRegisterBodyDecoder_ShouldOnlyApplyToRouter(t *testing.T) {
+	// given
+	getBodyRouter(WithBodyDecoder("text/x-client", func(_ io.Reader, _ http.Header, _ *openapi3.SchemaRef,
+		_ openapi3filter.EncodingFn) (interface{}, error) {
+		return map[string]interface{}{"name": "test"}, nil
+	}))
+	router, _ := getBodyRouter()
+	request := httptest.NewRequest(http.MethodPost, "/clients", strings.NewReader("test"))
+	request.Header.Set("Content-Type", "text/x-client")
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, request)
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "unsupported content type")
+}
+
+func TestRegisterBodyDecoder_ShouldValidateVendorJSON(t *testing.T) {
+	// given
+	router, _ := getBodyRouter()
+	request := httptest.NewRequest(http.MethodPost, "/clients", strings.NewReader(`{"id":42}`))
+	request.Header.Set("Content-Type", "application/vnd.clients+json")
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, request)
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"keyword":"required"`)
+}
+
+func TestBindBody_ShouldBindUntypedHandler(t *testing.T) {
+	// given
+	router, _ := getBodyRouter()
+	var client bodyTestClient
+	var bindErr error
+	router.HandleOperation("createClient", func(request *http.Request, _ map[string]string) (*Response, error) {
+		bindErr = BindBody(request, &client)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	request := httptest.NewRequest(http.MethodPost, "/clients", strings.NewReader(`<client><name>test</name></client>`))
+	request.Header.Set("Content-Type", "application/xml")
+
+	// when
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	// then
+	assert.Nil(t, bindErr)
+	assert.Equal(t, bodyTestClient{Name: "test"}, client)
+}
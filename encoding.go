@@ -0,0 +1,307 @@
+package openapirouter
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/invopop/yaml"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encoder writes the body of a Response in the format of a media type. It is registered for the media type with
+// Router.RegisterEncoder or the WithEncoder option.
+type Encoder func(writer io.Writer, body interface{}) error
+
+// EncodeJSON is the Encoder for application/json and all media types with the structured syntax suffix +json.
+func EncodeJSON(writer io.Writer, body interface{}) error {
+	return json.NewEncoder(writer).Encode(body)
+}
+
+// EncodeXML is the Encoder for application/xml, text/xml and all media types with the structured syntax suffix +xml.
+func EncodeXML(writer io.Writer, body interface{}) error {
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(writer).Encode(body)
+}
+
+// EncodeYAML is the Encoder for application/yaml, application/x-yaml and all media types with the structured syntax
+// suffix +yaml. Like with EncodeJSON, the names of the fields are taken from their json tags.
+func EncodeYAML(writer io.Writer, body interface{}) error {
+	data, err := yaml.Marshal(body)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// EncodeText is the Encoder for text/plain. It writes strings, byte slices, fmt.Stringer and errors as they are and
+// fails for any other body.
+func EncodeText(writer io.Writer, body interface{}) error {
+	var text string
+	switch value := body.(type) {
+	case string:
+		text = value
+	case []byte:
+		_, err := writer.Write(value)
+		return err
+	case fmt.Stringer:
+		text = value.String()
+	case error:
+		text = value.Error()
+	default:
+		return fmt.Errorf("body of type %T can not be encoded as text", body)
+	}
+	_, err := io.WriteString(writer, text)
+	return err
+}
+
+// RegisterEncoder sets the Encoder for the media type, e.g. application/csv, or replaces a built-in Encoder. The media
+// type is offered in the content negotiation of every response, which documents it in the OpenAPI specification. An
+// Encoder registered for application/json, application/xml or application/yaml is also used for the media types with
+// the corresponding structured syntax suffix, unless they have their own Encoder.
+func (router *Router) RegisterEncoder(mediaType string, encoder Encoder) {
+	router.encoders.register(mediaType, encoder)
+}
+
+// encoderRegistry contains the Encoder for each media type the Router is able to write.
+type encoderRegistry struct {
+	encoders map[string]Encoder
+}
+
+// newEncoderRegistry creates the encoderRegistry with the built-in encoders for JSON, XML, YAML and plain text.
+func newEncoderRegistry() *encoderRegistry {
+	return &encoderRegistry{encoders: map[string]Encoder{
+		"application/json":   EncodeJSON,
+		"application/xml":    EncodeXML,
+		"text/xml":           EncodeXML,
+		"application/yaml":   EncodeYAML,
+		"application/x-yaml": EncodeYAML,
+		"text/plain":         EncodeText,
+	}}
+}
+
+// register sets the Encoder for the media type. An existing Encoder is replaced.
+func (registry *encoderRegistry) register(mediaType string, encoder Encoder) {
+	registry.encoders[normalizeMediaType(mediaType)] = encoder
+}
+
+// lookup returns the Encoder for the media type. Without an Encoder registered for the media type itself, the Encoder
+// of its structured syntax suffix is used, e.g. application/json for application/vnd.clients+json.
+func (registry *encoderRegistry) lookup(mediaType string) (Encoder, bool) {
+	mediaType = normalizeMediaType(mediaType)
+	if encoder, ok := registry.encoders[mediaType]; ok {
+		return encoder, true
+	}
+	if index := strings.LastIndex(mediaType, "+"); index >= 0 {
+		encoder, ok := registry.encoders["application/"+mediaType[index+1:]]
+		return encoder, ok
+	}
+	return nil, false
+}
+
+// negotiate selects the media type and the Encoder for the body of a successful Response of the operation. The media
+// types documented for the status code of the Response are negotiated against the Accept header of the request. If
+// none of them is acceptable, an HTTPError with the status code http.StatusNotAcceptable is returned. A nil Encoder is
+// returned, if the response has no body or no documented media type with an Encoder, so the Response is written
+// as before. A Content-Type set in the headers of the Response is used without negotiation. A streamed Body is
+// negotiated against all documented media types, since it is written without Encoder.
+func (registry *encoderRegistry) negotiate(request *http.Request, operation *openapi3.Operation,
+	response *Response) (string, Encoder, error) {
+	if response.Body == nil {
+		return "", nil, nil
+	}
+	if contentType, ok := response.header("Content-Type"); ok {
+		encoder, _ := registry.lookup(contentType)
+		return contentType, encoder, nil
+	}
+	content := documentedContent(operation, response.StatusCode)
+	stream := isStream(response.Body)
+	candidates := documentedMediaTypes(content)
+	if !stream {
+		candidates = registry.candidates(content, defaultMediaType(response))
+	}
+	if len(candidates) == 0 {
+		return "", nil, nil
+	}
+	mediaType, ok := selectMediaType(request.Header.Values("Accept"), candidates)
+	if !ok {
+		return "", nil, notAcceptableError(candidates)
+	}
+	if stream {
+		return mediaType, nil, nil
+	}
+	encoder, _ := registry.lookup(mediaType)
+	return contentTypeOf(mediaType), encoder, nil
+}
+
+// acceptable reports whether any media type documented for the successful responses of the operation is acceptable
+// for the request. It is true, if no media type with an Encoder is documented. Media types without an Encoder are
+// acceptable as well, since the handler function may stream a body of them.
+func (registry *encoderRegistry) acceptable(request *http.Request, operation *openapi3.Operation) (bool,
+	[]string) {
+	accept := request.Header.Values("Accept")
+	if len(accept) == 0 {
+		return true, nil
+	}
+	content := openapi3.Content{}
+	for status, response := range operation.Responses {
+		if (strings.HasPrefix(status, "2") || status == "default") && response.Value != nil {
+			for mediaType, value := range response.Value.Content {
+				content[mediaType] = value
+			}
+		}
+	}
+	if len(registry.candidates(content, "")) == 0 {
+		return true, nil
+	}
+	candidates := documentedMediaTypes(content)
+	_, ok := selectMediaType(accept, candidates)
+	return ok, candidates
+}
+
+// candidates returns the concrete media types of the content with a registered Encoder. The preferred media type is
+// the first candidate, if it is documented, and the others are sorted.
+func (registry *encoderRegistry) candidates(content openapi3.Content, preferred string) []string {
+	var candidates []string
+	for _, mediaType := range documentedMediaTypes(content) {
+		if _, ok := registry.lookup(mediaType); ok {
+			candidates = append(candidates, mediaType)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return normalizeMediaType(candidates[i]) == preferred && normalizeMediaType(candidates[j]) != preferred
+	})
+	return candidates
+}
+
+// documentedMediaTypes returns the sorted concrete media types of the content. Media ranges like image/* are skipped.
+func documentedMediaTypes(content openapi3.Content) []string {
+	var mediaTypes []string
+	for mediaType := range content {
+		if !strings.Contains(mediaType, "*") {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	sort.Strings(mediaTypes)
+	return mediaTypes
+}
+
+// documentedContent returns the content documented for the status code in the responses of the operation. The exact
+// status code takes precedence over the range of the status code, e.g. 2XX, which takes precedence over the default
+// response.
+func documentedContent(operation *openapi3.Operation, statusCode int) openapi3.Content {
+	code := strconv.Itoa(statusCode)
+	for _, status := range []string{code, code[:1] + "XX", "default"} {
+		if response := operation.Responses[status]; response != nil && response.Value != nil {
+			return response.Value.Content
+		}
+	}
+	return nil
+}
+
+// defaultMediaType returns the media type the body of the Response is written with without negotiation.
+func defaultMediaType(response *Response) string {
+	if _, ok := response.Body.(string); ok {
+		return "text/plain"
+	}
+	return "application/json"
+}
+
+// selectMediaType returns the candidate with the highest quality in the Accept header values. If several candidates
+// have the same quality, the first one is returned. Without Accept header, the first candidate is returned.
+func selectMediaType(accept []string, candidates []string) (string, bool) {
+	if len(accept) == 0 {
+		return candidates[0], true
+	}
+	ranges := parseAccept(accept)
+	selected, selectedQuality := "", 0.0
+	for _, candidate := range candidates {
+		if quality := acceptQuality(ranges, normalizeMediaType(candidate)); quality > selectedQuality {
+			selected, selectedQuality = candidate, quality
+		}
+	}
+	return selected, selectedQuality > 0
+}
+
+// mediaRange is a media range of the Accept header with its quality.
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept parses the media ranges of the Accept header values. Invalid media ranges are skipped.
+func parseAccept(accept []string) []mediaRange {
+	var ranges []mediaRange
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				if quality, err = strconv.ParseFloat(q, 64); err != nil {
+					continue
+				}
+			}
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+		}
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific media range matching the media type, or 0 if no media range
+// matches.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	quality, specificity := 0.0, -1
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	for _, accepted := range ranges {
+		current := -1
+		switch accepted.mediaType {
+		case mediaType:
+			current = 2
+		case mainType + "/*":
+			current = 1
+		case "*/*":
+			current = 0
+		}
+		if current > specificity {
+			quality, specificity = accepted.quality, current
+		}
+	}
+	return quality
+}
+
+// notAcceptableError returns the HTTPError for a request, which accepts none of the media types.
+func notAcceptableError(mediaTypes []string) *HTTPError {
+	return NewHTTPError(http.StatusNotAcceptable, "supported media types: "+strings.Join(mediaTypes, ", "))
+}
+
+// normalizeMediaType returns the media type without parameters in lower case.
+func normalizeMediaType(mediaType string) string {
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		return parsed
+	}
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// contentTypeOf returns the Content-Type header of the media type. Like without negotiation, text and JSON media types
+// are written with the charset utf-8.
+func contentTypeOf(mediaType string) string {
+	if strings.Contains(mediaType, ";") {
+		return mediaType
+	}
+	normalized := normalizeMediaType(mediaType)
+	if strings.HasPrefix(normalized, "text/") || normalized == "application/json" {
+		return mediaType + "; charset=utf-8"
+	}
+	return mediaType
+}
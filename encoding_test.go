@@ -0,0 +1,228 @@
+package openapirouter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const encodingTestSpec = `
+openapi: 3.0.3
+info:
+  title: Encoding-API
+  version: 1.0.0
+paths:
+  /clients/{id}:
+    get:
+      operationId: getClient
+      parameters:
+        - in: path
+          name: id
+          required: true
+          schema:
+            type: string
+        - in: query
+          name: limit
+          schema:
+            type: integer
+      responses:
+        '200':
+          description: client
+          content:
+            application/json: {}
+            application/xml: {}
+            application/x-yaml: {}
+            application/vnd.clients+json: {}
+  /report:
+    get:
+      operationId: getReport
+      responses:
+        '200':
+          description: report
+          content:
+            text/csv: {}
+`
+
+type encodingTestClient struct {
+	XMLName xml.Name `json:"-" xml:"client"`
+	ID      string   `json:"id" xml:"id"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+func getEncodingRouter(opts ...Option) (*Router, *int) {
+	router, err := NewRouterFromData([]byte(encodingTestSpec), opts...)
+	if err != nil {
+		panic(err)
+	}
+	calls := 0
+	router.HandleOperation("getClient", func(_ *http.Request, pathParams map[string]string) (*Response, error) {
+		calls++
+		return &Response{StatusCode: http.StatusOK, Body: encodingTestClient{ID: pathParams["id"], Name: "test"}}, nil
+	})
+	router.HandleOperation("getReport", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{StatusCode: http.StatusOK, Body: [][]string{{"id", "name"}, {"42", "test"}}}, nil
+	})
+	return router, &calls
+}
+
+func serveWithAccept(router *Router, path string, accept ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, value := range accept {
+		request.Header.Add("Accept", value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestContentNegotiation_ShouldSelectAcceptedMediaType(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      []string
+		contentType string
+		body        string
+	}{
+		{"without Accept", nil, "application/json; charset=utf-8", `{"id":"42","name":"test"}` + "\n"},
+		{"any media type", []string{"*/*"}, "application/json; charset=utf-8", `{"id":"42","name":"test"}` + "\n"},
+		{"xml", []string{"application/xml"}, "application/xml",
+			xml.Header + "<client><id>42</id><name>test</name></client>"},
+		{"yaml", []string{"text/html, application/x-yaml"}, "application/x-yaml", "id: \"42\"\nname: test\n"},
+		{"vendor json", []string{"application/vnd.clients+json"}, "application/vnd.clients+json",
+			`{"id":"42","name":"test"}` + "\n"},
+		{"quality", []string{"application/xml;q=0.5", "application/json;q=0.9"}, "application/json; charset=utf-8",
+			`{"id":"42","name":"test"}` + "\n"},
+		{"specific range", []string{"application/*;q=0.1, application/xml"}, "application/xml",
+			xml.Header + "<client><id>42</id><name>test</name></client>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, _ := getEncodingRouter()
+
+			// when
+			recorder := serveWithAccept(router, "/clients/42", test.accept...)
+
+			// then
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, test.contentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, test.body, recorder.Body.String())
+		})
+	}
+}
+
+func TestContentNegotiation_ShouldRejectUnacceptableRequest(t *testing.T) {
+	// given
+	router, calls := getEncodingRouter()
+
+	// when
+	recorder := serveWithAccept(router, "/clients/42", "text/html, application/json;q=0")
+
+	// then
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(),
+		"supported media types: application/json, application/vnd.clients+json, application/x-yaml, application/xml")
+	assert.Equal(t, 0, *calls)
+}
+
+func TestContentNegotiation_ShouldValidateRequestFirst(t *testing.T) {
+	// given
+	router, calls := getEncodingRouter()
+
+	// when
+	recorder := serveWithAccept(router, "/clients/42?limit=x", "text/html")
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestContentNegotiation_ShouldUseSpecifiedContentType(t *testing.T) {
+	// given
+	router, _ := getEncodingRouter()
+	router.HandleOperation("getClient", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       encodingTestClient{ID: "42", Name: "test"},
+			Headers:    map[string]string{"Content-Type": "application/xml"},
+		}, nil
+	})
+
+	// when
+	recorder := serveWithAccept(router, "/clients/42")
+
+	// then
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/xml", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, xml.Header+"<client><id>42</id><name>test</name></client>", recorder.Body.String())
+}
+
+func TestContentNegotiation_ShouldUseSpecifiedContentTypeOfAnyCase(t *testing.T) {
+	// given
+	router, _ := getEncodingRouter()
+	router.HandleOperation("getClient", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       encodingTestClient{ID: "42", Name: "test"},
+			Headers:    map[string]string{"content-type": "application/xml"},
+		}, nil
+	})
+
+	// when
+	recorder := serveWithAccept(router, "/clients/42", "application/json")
+
+	// then
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/xml", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, xml.Header+"<client><id>42</id><name>test</name></client>", recorder.Body.String())
+}
+
+func TestRegisterEncoder_ShouldEncodeCustomMediaType(t *testing.T) {
+	// given
+	router, _ := getEncodingRouter(WithEncoder("text/csv", func(writer io.Writer, body interface{}) error {
+		for _, row := range body.([][]string) {
+			if _, err := fmt.Fprintf(writer, "%s,%s\n", row[0], row[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	// when
+	recorder := serveWithAccept(router, "/report", "text/csv")
+
+	// then
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "id,name\n42,test\n", recorder.Body.String())
+}
+
+func TestRegisterEncoder_WithoutEncoder(t *testing.T) {
+	// given
+	router, _ := getEncodingRouter()
+
+	// when
+	recorder := serveWithAccept(router, "/report", "text/csv")
+
+	// then
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+}
+
+func TestContentNegotiation_EncodingFails(t *testing.T) {
+	// given
+	router, _ := getEncodingRouter()
+	router.RegisterEncoder("application/xml", func(_ io.Writer, _ interface{}) error {
+		return fmt.Errorf("encoding failed")
+	})
+
+	// when
+	recorder := serveWithAccept(router, "/clients/42", "application/xml")
+
+	// then
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+}
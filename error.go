@@ -1,12 +1,18 @@
 package openapirouter
 
 import (
+	"encoding/json"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
-const errorTypeUri = "https://developer.mozilla.org/de/docs/Web/HTTP/Status/"
+const (
+	errorTypeUri           = "https://developer.mozilla.org/de/docs/Web/HTTP/Status/"
+	problemTypeBlank       = "about:blank"
+	problemJSONContentType = "application/problem+json"
+)
 
 var (
 	errorNames = map[int]string{
@@ -33,15 +39,23 @@ type HTTPError struct {
 	Title string `json:"title"`
 	// additional details for the error, e.g. what went wrong
 	Details []string `json:"details"`
+	// URI reference that identifies the specific occurrence of the error
+	Instance string `json:"instance,omitempty"`
+	// additional members of the error, which are written next to the other fields
+	Extensions map[string]interface{} `json:"-"`
 }
 
 // NewHTTPError creates a new error with a specified statusCode and any number of details. The Title and Type of the
 // error is set to correspond with the status code.
 func NewHTTPError(statusCode int, details ...string) *HTTPError {
+	title, ok := errorNames[statusCode]
+	if !ok {
+		title = http.StatusText(statusCode)
+	}
 	return &HTTPError{
 		StatusCode: statusCode,
 		Type:       errorTypeUri + strconv.Itoa(statusCode),
-		Title:      errorNames[statusCode],
+		Title:      title,
 		Details:    details,
 	}
 }
@@ -51,6 +65,28 @@ func (er *HTTPError) Error() string {
 	return er.Title
 }
 
+// WithInstance sets the URI reference that identifies the specific occurrence of the error and returns the HTTPError.
+func (er *HTTPError) WithInstance(instance string) *HTTPError {
+	er.Instance = instance
+	return er
+}
+
+// WithExtension adds an additional member to the error and returns the HTTPError. Extensions are written next to the
+// other fields of the error, but cannot replace them.
+func (er *HTTPError) WithExtension(name string, value interface{}) *HTTPError {
+	if er.Extensions == nil {
+		er.Extensions = make(map[string]interface{})
+	}
+	er.Extensions[name] = value
+	return er
+}
+
+// MarshalJSON writes the fields of the HTTPError together with its Extensions.
+func (er HTTPError) MarshalJSON() ([]byte, error) {
+	type plainHTTPError HTTPError
+	return marshalWithExtensions(plainHTTPError(er), er.Extensions)
+}
+
 // ToResponse converts the HTTPError to a response to be written.
 func (er *HTTPError) ToResponse() *Response {
 	return &Response{
@@ -59,20 +95,225 @@ func (er *HTTPError) ToResponse() *Response {
 	}
 }
 
+// ToProblemDetails converts the HTTPError to a problem details document. If the Type of the HTTPError was not changed,
+// it is created from the baseTypeURI and the status code, or set to "about:blank" if the baseTypeURI is empty. The
+// Details are joined to the detail member.
+func (er *HTTPError) ToProblemDetails(baseTypeURI string) *ProblemDetails {
+	problemType := er.Type
+	if problemType == "" || problemType == errorTypeUri+strconv.Itoa(er.StatusCode) {
+		if baseTypeURI == "" {
+			problemType = problemTypeBlank
+		} else {
+			problemType = baseTypeURI + strconv.Itoa(er.StatusCode)
+		}
+	}
+	return &ProblemDetails{
+		Type:       problemType,
+		Title:      er.Title,
+		Status:     er.StatusCode,
+		Detail:     strings.Join(er.Details, "; "),
+		Instance:   er.Instance,
+		Extensions: er.Extensions,
+	}
+}
+
+// ProblemDetails is the problem details document for HTTP APIs as specified by RFC 9457 (formerly RFC 7807). It is
+// written with the media type application/problem+json by a Router created with the WithProblemDetails option.
+type ProblemDetails struct {
+	// URI reference that identifies the problem type
+	Type string `json:"type"`
+	// short summary of the problem type
+	Title string `json:"title,omitempty"`
+	// http status code of the response
+	Status int `json:"status,omitempty"`
+	// explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// URI reference that identifies the specific occurrence of the problem
+	Instance string `json:"instance,omitempty"`
+	// extension members of the problem, which are written next to the other members
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON writes the members of the ProblemDetails together with its Extensions.
+func (problem ProblemDetails) MarshalJSON() ([]byte, error) {
+	type plainProblemDetails ProblemDetails
+	return marshalWithExtensions(plainProblemDetails(problem), problem.Extensions)
+}
+
+// marshalWithExtensions marshals a struct to a JSON object and adds the extensions as additional members. Extensions
+// never replace the members of the struct.
+func marshalWithExtensions(value interface{}, extensions map[string]interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil || len(extensions) == 0 {
+		return data, err
+	}
+	members := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	for name, extension := range extensions {
+		if _, ok := members[name]; ok {
+			continue
+		}
+		if members[name], err = json.Marshal(extension); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(members)
+}
+
 // The errorMapper is used to map any error to an HTTP response.
 type errorMapper struct {
-	errorMapping map[reflect.Type]*HTTPError
+	errorMappings []errorMapping
+	// fallback creates the response for errors without a matching errorMapping
+	fallback func(error) *Response
+	// problemDetails enables responses in the format of RFC 9457 with the problemTypeURI as base for the type member
+	problemDetails bool
+	problemTypeURI string
+}
+
+// errorMapping maps every error the mapError function accepts to a Response. The errorType is only set for mappings
+// by type, so they can be replaced.
+type errorMapping struct {
+	errorType reflect.Type
+	mapError  func(error) (*Response, bool)
+}
+
+// newErrorMapper creates an errorMapper without any mappings.
+func newErrorMapper() *errorMapper {
+	return &errorMapper{}
+}
+
+// addTypeMapping maps all errors of the same type as err to the HTTPError. An existing mapping for the type is
+// replaced, but keeps its priority.
+func (mapper *errorMapper) addTypeMapping(err error, result *HTTPError) {
+	errorType := reflect.TypeOf(err)
+	mapping := errorMapping{
+		errorType: errorType,
+		mapError: func(err error) (*Response, bool) {
+			if !walkErrorChain(err, func(e error) bool { return reflect.TypeOf(e) == errorType }) {
+				return nil, false
+			}
+			return mapper.toResponse(result), true
+		},
+	}
+	for i := range mapper.errorMappings {
+		if mapper.errorMappings[i].errorType == errorType {
+			mapper.errorMappings[i] = mapping
+			return
+		}
+	}
+	mapper.errorMappings = append(mapper.errorMappings, mapping)
+}
+
+// addSentinelMapping maps all errors matching the sentinel error in terms of errors.Is to the HTTPError.
+func (mapper *errorMapper) addSentinelMapping(sentinel error, result *HTTPError) {
+	mapper.addMapper(func(err error) (*Response, bool) {
+		if !walkErrorChain(err, func(e error) bool { return isSentinel(e, sentinel) }) {
+			return nil, false
+		}
+		return mapper.toResponse(result), true
+	})
+}
+
+// addMapper adds a function which maps every error it accepts to a Response.
+func (mapper *errorMapper) addMapper(mapError func(error) (*Response, bool)) {
+	mapper.errorMappings = append(mapper.errorMappings, errorMapping{mapError: mapError})
 }
 
-// mapError receives an error and returns the fitting Response specified by the errorMapping.
+// mapError receives an error and returns the fitting Response. An HTTPError is mapped to itself. Any other error is
+// mapped by the first errorMapping in order of registration, which accepts the error or any error wrapped by it. If
+// no errorMapping matches, an HTTPError wrapped by the error is used. Unknown errors are mapped by the fallback, which
+// responds with an Internal Server Error by default.
 func (mapper errorMapper) mapError(err error) *Response {
+	if result, ok := err.(*HTTPError); ok {
+		return mapper.toResponse(result)
+	}
+	for _, mapping := range mapper.errorMappings {
+		if response, ok := mapping.mapError(err); ok {
+			return response
+		}
+	}
 	var result *HTTPError
-	var ok bool
-	if result, ok = err.(*HTTPError); !ok {
-		result, ok = mapper.errorMapping[reflect.TypeOf(err)]
-		if !ok {
-			return error500Response
+	if walkErrorChain(err, func(e error) bool {
+		result, _ = e.(*HTTPError)
+		return result != nil
+	}) {
+		return mapper.toResponse(result)
+	}
+	if mapper.fallback != nil {
+		return mapper.fallback(err)
+	}
+	return mapper.toResponse(NewHTTPError(http.StatusInternalServerError))
+}
+
+// MapError adds a function to the error mapper of the Router, which maps all errors of type T to an HTTPError, even if
+// they are wrapped by another error. In contrast to Router.AddErrorMapping, the HTTPError is created from the actual
+// error, so it can contain information like the ID of a resource that was not found. If the function returns nil, the
+// error is mapped by the next matching mapping.
+func MapError[T error](router *Router, mapFunc func(T) *HTTPError) {
+	mapper := router.errMapper
+	mapper.addMapper(func(err error) (*Response, bool) {
+		var target T
+		if !walkErrorChain(err, func(e error) bool {
+			var ok bool
+			target, ok = e.(T)
+			return ok
+		}) {
+			return nil, false
 		}
+		result := mapFunc(target)
+		if result == nil {
+			return nil, false
+		}
+		return mapper.toResponse(result), true
+	})
+}
+
+// walkErrorChain visits the error and all errors wrapped by it depth-first until visit returns true. Both, errors
+// wrapping a single error with Unwrap() error and errors wrapping multiple errors with Unwrap() []error, are
+// supported.
+func walkErrorChain(err error, visit func(error) bool) bool {
+	for err != nil {
+		if visit(err) {
+			return true
+		}
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapper.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, wrapped := range wrapper.Unwrap() {
+				if walkErrorChain(wrapped, visit) {
+					return true
+				}
+			}
+			return false
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// isSentinel reports whether the error matches the sentinel error, either by equality or by its own Is method.
+func isSentinel(err error, sentinel error) bool {
+	if reflect.TypeOf(err).Comparable() && err == sentinel {
+		return true
+	}
+	if matcher, ok := err.(interface{ Is(error) bool }); ok {
+		return matcher.Is(sentinel)
+	}
+	return false
+}
+
+// toResponse converts an HTTPError to the Response to be written, either as HTTPError itself or as ProblemDetails.
+func (mapper errorMapper) toResponse(er *HTTPError) *Response {
+	if !mapper.problemDetails {
+		return er.ToResponse()
+	}
+	return &Response{
+		StatusCode: er.StatusCode,
+		Body:       er.ToProblemDetails(mapper.problemTypeURI),
+		Headers:    map[string]string{"Content-Type": problemJSONContentType},
 	}
-	return result.ToResponse()
 }
@@ -1,15 +1,17 @@
 package openapirouter
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
-	"reflect"
 	"testing"
 )
 
 func TestErrorMapper_ShouldMapHttpErrorToResponseByDefault(t *testing.T) {
 	//given
-	mapper := errorMapper{errorMapping: make(map[reflect.Type]*HTTPError)}
+	mapper := newErrorMapper()
 	err := NewHTTPError(http.StatusNotFound)
 
 	//when
@@ -29,11 +31,8 @@ func (e *ExampleError) Error() string {
 
 func TestErrorMapper_ShouldMapKnownErrorToResponse(t *testing.T) {
 	//given
-	mapper := errorMapper{
-		errorMapping: map[reflect.Type]*HTTPError{
-			reflect.TypeOf(&ExampleError{}): NewHTTPError(http.StatusBadGateway),
-		},
-	}
+	mapper := newErrorMapper()
+	mapper.addTypeMapping(&ExampleError{}, NewHTTPError(http.StatusBadGateway))
 	err := &ExampleError{}
 
 	//when
@@ -46,11 +45,8 @@ func TestErrorMapper_ShouldMapKnownErrorToResponse(t *testing.T) {
 
 func TestErrorMapper_ShouldMapKnownErrorToResponseWithDetails(t *testing.T) {
 	//given
-	mapper := errorMapper{
-		errorMapping: map[reflect.Type]*HTTPError{
-			reflect.TypeOf(&ExampleError{}): NewHTTPError(http.StatusBadGateway, "detail1", "detail2"),
-		},
-	}
+	mapper := newErrorMapper()
+	mapper.addTypeMapping(&ExampleError{}, NewHTTPError(http.StatusBadGateway, "detail1", "detail2"))
 	err := &ExampleError{}
 
 	//when
@@ -63,7 +59,7 @@ func TestErrorMapper_ShouldMapKnownErrorToResponseWithDetails(t *testing.T) {
 
 func TestErrorMapper_ShouldMapKnownErrorToInternalServerError(t *testing.T) {
 	//given
-	mapper := errorMapper{errorMapping: make(map[reflect.Type]*HTTPError)}
+	mapper := newErrorMapper()
 	err := &ExampleError{}
 
 	//when
@@ -73,3 +69,285 @@ func TestErrorMapper_ShouldMapKnownErrorToInternalServerError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, result.StatusCode)
 	assert.Equal(t, *NewHTTPError(http.StatusInternalServerError), result.Body)
 }
+
+func TestNewHTTPError_ShouldUseStatusTextAsDefaultTitle(t *testing.T) {
+	//when
+	result := NewHTTPError(http.StatusNotAcceptable)
+
+	//then
+	assert.Equal(t, "Not Acceptable", result.Title)
+	assert.Equal(t, "https://developer.mozilla.org/de/docs/Web/HTTP/Status/406", result.Type)
+}
+
+func TestHTTPError_ShouldMarshalExtensions(t *testing.T) {
+	//given
+	err := NewHTTPError(http.StatusNotFound, "client not found").
+		WithInstance("/clients/42").
+		WithExtension("client", "42").
+		WithExtension("title", "ignored")
+
+	//when
+	result, marshalErr := json.Marshal(err)
+
+	//then
+	assert.Nil(t, marshalErr)
+	assert.JSONEq(t, `{
+		"StatusCode": 404,
+		"type": "https://developer.mozilla.org/de/docs/Web/HTTP/Status/404",
+		"title": "Not found",
+		"details": ["client not found"],
+		"instance": "/clients/42",
+		"client": "42"
+	}`, string(result))
+}
+
+func TestHTTPError_ToProblemDetails(t *testing.T) {
+	//given
+	err := NewHTTPError(http.StatusNotFound, "client not found", "check the id").WithExtension("client", "42")
+
+	//when
+	result := err.ToProblemDetails("https://example.com/problems/")
+	data, marshalErr := json.Marshal(result)
+
+	//then
+	assert.Equal(t, "https://example.com/problems/404", result.Type)
+	assert.Equal(t, "client not found; check the id", result.Detail)
+	assert.Nil(t, marshalErr)
+	assert.JSONEq(t, `{
+		"type": "https://example.com/problems/404",
+		"title": "Not found",
+		"status": 404,
+		"detail": "client not found; check the id",
+		"client": "42"
+	}`, string(data))
+}
+
+func TestHTTPError_ToProblemDetails_ShouldKeepCustomType(t *testing.T) {
+	//given
+	err := NewHTTPError(http.StatusConflict)
+	err.Type = "https://example.com/problems/out-of-credit"
+
+	//when
+	custom := err.ToProblemDetails("https://example.com/problems/")
+	blank := NewHTTPError(http.StatusConflict).ToProblemDetails("")
+
+	//then
+	assert.Equal(t, "https://example.com/problems/out-of-credit", custom.Type)
+	assert.Equal(t, "about:blank", blank.Type)
+}
+
+func TestErrorMapper_ShouldMapToProblemDetails(t *testing.T) {
+	//given
+	mapper := &errorMapper{problemDetails: true, problemTypeURI: "https://example.com/problems/"}
+	mapper.addTypeMapping(&ExampleError{}, NewHTTPError(http.StatusBadGateway))
+
+	//when
+	result := mapper.mapError(&ExampleError{})
+
+	//then
+	assert.Equal(t, http.StatusBadGateway, result.StatusCode)
+	assert.Equal(t, "application/problem+json", result.Headers["Content-Type"])
+	assert.Equal(t, NewHTTPError(http.StatusBadGateway).ToProblemDetails("https://example.com/problems/"), result.Body)
+}
+
+func TestErrorMapper_ShouldMapWrappedKnownError(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.addTypeMapping(&ExampleError{}, NewHTTPError(http.StatusBadGateway))
+	err := fmt.Errorf("loading client: %w", &ExampleError{})
+
+	//when
+	result := mapper.mapError(err)
+
+	//then
+	assert.Equal(t, http.StatusBadGateway, result.StatusCode)
+}
+
+func TestErrorMapper_ShouldMapWrappedHttpError(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	err := fmt.Errorf("loading client: %w", NewHTTPError(http.StatusNotFound, "unknown client"))
+
+	//when
+	result := mapper.mapError(err)
+
+	//then
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	assert.Equal(t, *NewHTTPError(http.StatusNotFound, "unknown client"), result.Body)
+}
+
+var errSentinel = errors.New("sentinel")
+
+type multiError []error
+
+func (e multiError) Error() string {
+	return "multiple errors"
+}
+
+func (e multiError) Unwrap() []error {
+	return e
+}
+
+func TestErrorMapper_ShouldMapErrorsWrappedInMultiError(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.addSentinelMapping(errSentinel, NewHTTPError(http.StatusConflict))
+	err := multiError{errors.New("other"), fmt.Errorf("wrapped: %w", errSentinel)}
+
+	//when
+	result := mapper.mapError(err)
+
+	//then
+	assert.Equal(t, http.StatusConflict, result.StatusCode)
+}
+
+func TestErrorMapper_ShouldMapSentinelErrorsOnlyByValue(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.addSentinelMapping(errSentinel, NewHTTPError(http.StatusConflict))
+
+	//when
+	sentinelResult := mapper.mapError(fmt.Errorf("wrapped: %w", errSentinel))
+	otherResult := mapper.mapError(errors.New("sentinel"))
+
+	//then
+	assert.Equal(t, http.StatusConflict, sentinelResult.StatusCode)
+	assert.Equal(t, http.StatusInternalServerError, otherResult.StatusCode)
+}
+
+type matchingError struct {
+}
+
+func (e matchingError) Error() string {
+	return "matching error"
+}
+
+func (e matchingError) Is(target error) bool {
+	return target == errSentinel
+}
+
+func TestErrorMapper_ShouldMapSentinelErrorsWithIsMethod(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.addSentinelMapping(errSentinel, NewHTTPError(http.StatusConflict))
+
+	//when
+	result := mapper.mapError(matchingError{})
+
+	//then
+	assert.Equal(t, http.StatusConflict, result.StatusCode)
+}
+
+func TestErrorMapper_ShouldUseFirstMatchingMapping(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.addSentinelMapping(errSentinel, NewHTTPError(http.StatusConflict))
+	mapper.addTypeMapping(&ExampleError{}, NewHTTPError(http.StatusBadGateway))
+	err := multiError{&ExampleError{}, errSentinel}
+
+	//when
+	result := mapper.mapError(err)
+
+	//then
+	assert.Equal(t, http.StatusConflict, result.StatusCode)
+}
+
+func TestErrorMapper_ShouldPreferMappingOverWrappedHttpError(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.addSentinelMapping(errSentinel, NewHTTPError(http.StatusConflict))
+	err := multiError{NewHTTPError(http.StatusNotFound), errSentinel}
+
+	//when
+	result := mapper.mapError(err)
+
+	//then
+	assert.Equal(t, http.StatusConflict, result.StatusCode)
+}
+
+func TestErrorMapper_ShouldReplaceMappingOfSameType(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.addTypeMapping(&ExampleError{}, NewHTTPError(http.StatusBadGateway))
+	mapper.addTypeMapping(&ExampleError{}, NewHTTPError(http.StatusServiceUnavailable))
+
+	//when
+	result := mapper.mapError(&ExampleError{})
+
+	//then
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+	assert.Len(t, mapper.errorMappings, 1)
+}
+
+type NotFoundError struct {
+	ID string
+}
+
+func (e *NotFoundError) Error() string {
+	return "not found: " + e.ID
+}
+
+func TestErrorMapper_ShouldMapWithMapperFunction(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.addMapper(func(err error) (*Response, bool) {
+		if err.Error() != "custom" {
+			return nil, false
+		}
+		return &Response{StatusCode: http.StatusTeapot, Body: "custom"}, true
+	})
+
+	//when
+	customResult := mapper.mapError(errors.New("custom"))
+	otherResult := mapper.mapError(errors.New("other"))
+
+	//then
+	assert.Equal(t, http.StatusTeapot, customResult.StatusCode)
+	assert.Equal(t, "custom", customResult.Body)
+	assert.Equal(t, http.StatusInternalServerError, otherResult.StatusCode)
+}
+
+func TestMapError_ShouldCreateHttpErrorFromActualError(t *testing.T) {
+	//given
+	router := &Router{errMapper: newErrorMapper()}
+	MapError(router, func(err *NotFoundError) *HTTPError {
+		return NewHTTPError(http.StatusNotFound, "client "+err.ID+" does not exist")
+	})
+
+	//when
+	result := router.errMapper.mapError(fmt.Errorf("loading client: %w", &NotFoundError{ID: "42"}))
+
+	//then
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	assert.Equal(t, *NewHTTPError(http.StatusNotFound, "client 42 does not exist"), result.Body)
+}
+
+func TestMapError_ShouldSkipMappingReturningNil(t *testing.T) {
+	//given
+	router := &Router{errMapper: newErrorMapper()}
+	MapError(router, func(err *NotFoundError) *HTTPError {
+		return nil
+	})
+	router.errMapper.addTypeMapping(&NotFoundError{}, NewHTTPError(http.StatusGone))
+
+	//when
+	result := router.errMapper.mapError(&NotFoundError{ID: "42"})
+
+	//then
+	assert.Equal(t, http.StatusGone, result.StatusCode)
+}
+
+func TestErrorMapper_ShouldUseFallbackForUnknownErrors(t *testing.T) {
+	//given
+	mapper := newErrorMapper()
+	mapper.fallback = func(err error) *Response {
+		return NewHTTPError(http.StatusServiceUnavailable, err.Error()).ToResponse()
+	}
+
+	//when
+	result := mapper.mapError(errors.New("database unavailable"))
+
+	//then
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+	assert.Equal(t, *NewHTTPError(http.StatusServiceUnavailable, "database unavailable"), result.Body)
+}
@@ -1,13 +1,22 @@
 package openapirouter
 
 import (
+	"errors"
 	"github.com/getkin/kin-openapi/openapi3filter"
+	"log/slog"
 	"net/http"
 )
 
 type contextKey int
 
-const pathParamsKey contextKey = iota
+const (
+	pathParamsKey contextKey = iota
+	authenticationKey
+	routeKey
+	observationKey
+	uploadKey
+	decodersKey
+)
 
 // HandleRequestFunction is a custom function to specify the implementation of an HTTP endpoint. It does not receive the
 // http.ResponseWriter for the request since it is written by the requestHandler. The content of this response is
@@ -22,19 +31,78 @@ type requestHandler struct {
 	errMapper       *errorMapper
 	handlerFunction HandleRequestFunction
 	options         *openapi3filter.Options
+	logger          *slog.Logger
+	panicHandler    PanicHandler
+	encoders        *encoderRegistry
+	dispatchesAuth  bool
 }
 
 // implementation of http.Handler that extracts the pathParameters from the request's context and invokes the
-// handlerFunction. If an error occurs calling the handlerFunction, it is mapped by the Router's errorMapper
+// handlerFunction. If an error occurs calling the handlerFunction, it is mapped by the Router's errorMapper. A panic of
+// the handlerFunction is recovered and mapped as PanicError. The Body of a successful Response is written with the
+// media type negotiated from the Accept header of the request. A streamed Body is closed after the response was
+// written or as soon as the client disconnects.
 func (handler *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	pathParams, ok := request.Context().Value(pathParamsKey).(map[string]string)
-	response := error500Response
-	var err error
+	response := handler.errMapper.toResponse(NewHTTPError(http.StatusInternalServerError))
+	var contentType string
+	var encoder Encoder
 	if ok {
-		response, err = handler.handlerFunction(request, pathParams)
+		var err error
+		response, err = handler.callHandlerFunction(request, pathParams)
+		if response != nil {
+			defer handler.closeBody(request, closeOnDone(request.Context(), response.Body))
+		}
 		if err != nil {
+			response = handler.handleError(request, err)
+		} else if contentType, encoder, err = handler.negotiate(request, response); err != nil {
+			reject(request.Context(), RejectionNotAcceptable, err)
 			response = handler.errMapper.mapError(err)
 		}
 	}
-	response.write(writer)
+	write := response.write
+	if encoder != nil || contentType != "" && isStream(response.Body) {
+		write = func(writer http.ResponseWriter) error { return response.encode(writer, contentType, encoder) }
+	}
+	if err := write(writer); err != nil {
+		if request.Context().Err() != nil {
+			handler.logger.DebugContext(request.Context(), "Client disconnected while writing response",
+				"error", err)
+			return
+		}
+		handler.logger.ErrorContext(request.Context(), "Could not write response", "error", err)
+	}
+}
+
+// closeBody closes the Body of the Response returned by the handlerFunction and logs an error of closing it.
+func (handler *requestHandler) closeBody(request *http.Request, close func() error) {
+	if err := close(); err != nil {
+		handler.logger.ErrorContext(request.Context(), "Could not close response body", "error", err)
+	}
+}
+
+// handleError maps the error returned by the handlerFunction to a Response and records it in the log. Errors mapped to
+// a server error are recorded for the PhaseTracer of the Router as well. A PanicError is not logged again, since the
+// panic was already logged with its stack.
+func (handler *requestHandler) handleError(request *http.Request, err error) *Response {
+	response := handler.errMapper.mapError(err)
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		handler.logger.ErrorContext(request.Context(), "Request handler failed",
+			"operationId", OperationIDFromContext(request.Context()), "status", response.StatusCode, "error", err)
+	}
+	if observation, ok := request.Context().Value(observationKey).(*observation); ok &&
+		response.StatusCode >= http.StatusInternalServerError {
+		observation.handlerErr = err
+	}
+	return response
+}
+
+// negotiate selects the media type and the Encoder of the Response for the operation of the request.
+func (handler *requestHandler) negotiate(request *http.Request, response *Response) (string, Encoder, error) {
+	route, ok := RouteFromContext(request.Context())
+	if !ok || handler.encoders == nil {
+		return "", nil, nil
+	}
+	return handler.encoders.negotiate(request, route.Operation, response)
 }
@@ -3,6 +3,7 @@ package openapirouter
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -17,7 +18,11 @@ func handleRequest(_ *http.Request, _ map[string]string) (*Response, error) {
 	}, nil
 }
 
-var handler = &requestHandler{handlerFunction: handleRequest}
+var handler = &requestHandler{
+	errMapper:       newErrorMapper(),
+	handlerFunction: handleRequest,
+	logger:          slog.Default(),
+}
 
 func TestRequestHandler_ShouldInvokeHandlerFunction(t *testing.T) {
 	// given
@@ -0,0 +1,161 @@
+package openapirouter
+
+import (
+	"context"
+	"net/http"
+)
+
+// reasons for the rejection of a request by the Router, which are returned by RejectionFromContext
+const (
+	RejectionInvalidRequest   = "invalid_request"
+	RejectionUnauthorized     = "unauthorized"
+	RejectionForbidden        = "forbidden"
+	RejectionNotFound         = "not_found"
+	RejectionMethodNotAllowed = "method_not_allowed"
+	RejectionNotImplemented   = "not_implemented"
+	RejectionNotAcceptable    = "not_acceptable"
+	RejectionPayloadTooLarge  = "payload_too_large"
+	RejectionInternalError    = "internal_error"
+)
+
+// WithInstrumentation adds middleware observing every request served by the Router, e.g. to record metrics or traces.
+// Unlike the middleware added with Use, it wraps the access log as well, so a span started by the instrumentation is
+// available to the log entries. The route of the request is available by RouteInfoFromContext and the reason why the
+// Router rejected the request by RejectionFromContext, after the wrapped handler returned. The subpackages metrics and
+// tracing provide instrumentation with Prometheus and OpenTelemetry.
+func WithInstrumentation(middlewares ...Middleware) Option {
+	return func(router *Router) {
+		router.instrumentation = append(router.instrumentation, middlewares...)
+	}
+}
+
+// PhaseTracer starts a span for a phase of the handling of a request by the Router, which is either "validate request"
+// or "handle request". It returns the context of the phase and a function ending the span. The function is called with
+// the error of the phase, which is nil if the phase succeeded or the handler function failed with a client error.
+type PhaseTracer func(ctx context.Context, name string) (context.Context, func(err error))
+
+// WithPhaseTracer traces the phases of the handling of every request served by the Router with the PhaseTracer.
+func WithPhaseTracer(tracer PhaseTracer) Option {
+	return func(router *Router) {
+		router.phaseTracer = tracer
+	}
+}
+
+// startPhase starts the phase of the handling of a request with the PhaseTracer of the Router, if one is set.
+func (router *Router) startPhase(ctx context.Context, name string) (context.Context, func(err error)) {
+	if router.phaseTracer == nil {
+		return ctx, func(error) {}
+	}
+	return router.phaseTracer(ctx, name)
+}
+
+// observation collects information about the handling of a request by the Router, which is not visible in the
+// response, e.g. the reason why the request was rejected and the error causing it.
+type observation struct {
+	rejection  string
+	err        error
+	handlerErr error
+}
+
+// observe returns the observation of the request. If the request has no observation yet, a new one is added to a copy
+// of the request, which is returned.
+func observe(request *http.Request) (*http.Request, *observation) {
+	if observation, ok := request.Context().Value(observationKey).(*observation); ok {
+		return request, observation
+	}
+	observation := &observation{}
+	return request.WithContext(context.WithValue(request.Context(), observationKey, observation)), observation
+}
+
+// reject records the reason why the request with the context was rejected by the Router and the error causing it,
+// which may be nil.
+func reject(ctx context.Context, reason string, err error) {
+	if observation, ok := ctx.Value(observationKey).(*observation); ok {
+		observation.rejection = reason
+		observation.err = err
+	}
+}
+
+// RejectionFromContext returns the reason why the Router rejected the request with the context, e.g.
+// RejectionInvalidRequest, and the error causing it, which may be nil. The reason is empty, if the request was not
+// rejected or has not been handled yet.
+func RejectionFromContext(ctx context.Context) (string, error) {
+	if observation, ok := ctx.Value(observationKey).(*observation); ok {
+		return observation.rejection, observation.err
+	}
+	return "", nil
+}
+
+// rejectionReason returns the reason of the rejection of a request, which failed the validation with the status code.
+// Server errors, e.g. because of a missing authenticator, are not attributed to the request.
+func rejectionReason(statusCode int) string {
+	if statusCode >= http.StatusInternalServerError {
+		return RejectionInternalError
+	}
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return RejectionUnauthorized
+	case http.StatusForbidden:
+		return RejectionForbidden
+	case http.StatusRequestEntityTooLarge:
+		return RejectionPayloadTooLarge
+	default:
+		return RejectionInvalidRequest
+	}
+}
+
+// StatusRecorder is an http.ResponseWriter which records the status code and the size of the response body, e.g. for
+// the access log or instrumentation.
+type StatusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	size       int
+}
+
+// NewStatusRecorder creates a StatusRecorder writing to the http.ResponseWriter.
+func NewStatusRecorder(writer http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: writer}
+}
+
+// WriteHeader records the status code and writes it to the underlying http.ResponseWriter.
+func (recorder *StatusRecorder) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = statusCode
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the size of the data and writes it to the underlying http.ResponseWriter.
+func (recorder *StatusRecorder) Write(data []byte) (int, error) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusOK
+	}
+	size, err := recorder.ResponseWriter.Write(data)
+	recorder.size += size
+	return size, err
+}
+
+// Flush sends the buffered data to the client, if the underlying http.ResponseWriter supports it.
+func (recorder *StatusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (recorder *StatusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// Status returns the recorded status code. If nothing was written, the status code is http.StatusOK.
+func (recorder *StatusRecorder) Status() int {
+	if recorder.statusCode == 0 {
+		return http.StatusOK
+	}
+	return recorder.statusCode
+}
+
+// Size returns the number of bytes written to the response body.
+func (recorder *StatusRecorder) Size() int {
+	return recorder.size
+}
@@ -0,0 +1,65 @@
+package openapirouter
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithInstrumentation_ShouldObserveRejections(t *testing.T) {
+	// given
+	var operationID, reason string
+	var rejectionErr error
+	var status int
+	instrumentation := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			recorder := NewStatusRecorder(writer)
+			next.ServeHTTP(recorder, request)
+			operationID = OperationIDFromContext(request.Context())
+			reason, rejectionErr = RejectionFromContext(request.Context())
+			status = recorder.Status()
+		})
+	}
+	router, err := NewRouter("testdata/test-api.yaml", WithInstrumentation(instrumentation))
+	assert.Nil(t, err)
+	router.HandleOperation("getQuery", handleNoContent)
+
+	// when
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/query", nil))
+
+	// then
+	assert.Equal(t, "getQuery", operationID)
+	assert.Equal(t, RejectionInvalidRequest, reason)
+	assert.NotNil(t, rejectionErr)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestWithPhaseTracer_ShouldTracePhases(t *testing.T) {
+	// given
+	phaseErrors := make(map[string]error)
+	var phases []string
+	tracer := func(ctx context.Context, name string) (context.Context, func(err error)) {
+		phases = append(phases, name)
+		return ctx, func(err error) {
+			phaseErrors[name] = err
+		}
+	}
+	router, err := NewRouter("testdata/test-api.yaml", WithPhaseTracer(tracer))
+	assert.Nil(t, err)
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, errors.New("database unavailable")
+	})
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	// then
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, []string{"validate request", "handle request"}, phases)
+	assert.Nil(t, phaseErrors["validate request"])
+	assert.EqualError(t, phaseErrors["handle request"], "database unavailable")
+}
@@ -0,0 +1,421 @@
+package openapirouter
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // register the hash functions of the supported algorithms
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is wrapped by the AuthenticationError returned by the JWT authenticator if the request contains
+	// no bearer token, or the token is malformed, not signed by a key of the KeySet, expired or issued for another
+	// issuer or audience.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrInsufficientScope is wrapped by the forbidden AuthenticationError returned by the JWT authenticator if a
+	// valid token does not grant all scopes required by the security requirement of the operation.
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+// KeySet contains the public keys used to verify the signatures of JSON Web Tokens, identified by their key ID.
+// RSA, ECDSA and Ed25519 keys are supported.
+type KeySet struct {
+	keys map[string]jsonWebKey
+}
+
+// jsonWebKey is a public key of a KeySet with the algorithm it may be used for, if it is restricted.
+type jsonWebKey struct {
+	key       crypto.PublicKey
+	algorithm string
+}
+
+// NewKeySet creates an empty KeySet. Keys are added with AddKey.
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]jsonWebKey)}
+}
+
+// AddKey adds a public key with the key ID to the KeySet. The key must be an *rsa.PublicKey, an *ecdsa.PublicKey or
+// an ed25519.PublicKey.
+func (keySet *KeySet) AddKey(keyID string, key crypto.PublicKey) error {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		keySet.keys[keyID] = jsonWebKey{key: key}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// jwk contains the members of a JSON Web Key, which are needed to create its public key. Other members like "x5c" or
+// "key_ops" are ignored.
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// ParseJWKS creates a KeySet from a JSON Web Key Set according to RFC 7517. Keys which are not used for signatures
+// are skipped.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	keySet := NewKeySet()
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("key %q could not be parsed: %w", jwk.KeyID, err)
+		}
+		keySet.keys[jwk.KeyID] = jsonWebKey{key: key, algorithm: jwk.Algorithm}
+	}
+	return keySet, nil
+}
+
+// LoadJWKS creates a KeySet from the file with the JSON Web Key Set at the path.
+func LoadJWKS(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// parseJWK creates the public key of a single JSON Web Key.
+func parseJWK(jwk jwk) (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(),
+			"P-521": elliptic.P521()}
+		curve, ok := curves[jwk.Curve]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
+
+// decodeBigInt decodes a base64url encoded unsigned integer of a JSON Web Key.
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("missing key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// JWTOptions configures the validation of the claims of JSON Web Tokens by the JWT authenticator.
+type JWTOptions struct {
+	// required value of the "iss" claim, not checked if empty
+	Issuer string
+	// value required in the "aud" claim, not checked if empty
+	Audience string
+	// tolerated clock skew for the "exp" and "nbf" claims
+	Leeway time.Duration
+	// returns the current time, time.Now if nil
+	Now func() time.Time
+}
+
+// JWTClaims contains the claims of a valid JSON Web Token. It is the principal returned by the JWT authenticator.
+type JWTClaims map[string]interface{}
+
+// Subject returns the "sub" claim of the token.
+func (claims JWTClaims) Subject() string {
+	subject, _ := claims["sub"].(string)
+	return subject
+}
+
+// Scopes returns the scopes granted by the token. They are read from the space-delimited "scope" claim according to
+// RFC 8693 or the "scp" claim, which is used by some identity providers as string or array.
+func (claims JWTClaims) Scopes() []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	switch scp := claims["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		var scopes []string
+		for _, scope := range scp {
+			if value, ok := scope.(string); ok {
+				scopes = append(scopes, value)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
+// NewJWTAuthenticator creates an Authenticator for security schemes of the type http with the scheme bearer, oauth2
+// and openIdConnect. It reads the JSON Web Token from the bearer token of the Authorization header and verifies its
+// signature with the keys of the KeySet. The claims "exp" and "nbf" are always validated, "iss" and "aud" according to
+// the JWTOptions. Tokens without "exp" claim are rejected. Additionally, the token has to grant all scopes listed in
+// the security requirement of the operation. The JWTClaims of a valid token are returned as principal.
+func NewJWTAuthenticator(keySet *KeySet, options JWTOptions) Authenticator {
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	return func(_ context.Context, input *openapi3filter.AuthenticationInput) (interface{}, error) {
+		if !isBearerScheme(input.SecurityScheme) {
+			return nil, fmt.Errorf("security scheme %s does not use bearer tokens", input.SecuritySchemeName)
+		}
+		token, ok := bearerToken(input.RequestValidationInput.Request.Header.Get("Authorization"))
+		if !ok {
+			return nil, NewUnauthenticatedError(fmt.Errorf("%w: no bearer token", ErrInvalidToken))
+		}
+		claims, err := keySet.verify(token)
+		if err == nil {
+			err = options.validate(claims)
+		}
+		if err != nil {
+			return nil, NewUnauthenticatedError(fmt.Errorf("%w: %v", ErrInvalidToken, err)).WithCode("invalid_token")
+		}
+		granted := make(map[string]bool)
+		for _, scope := range claims.Scopes() {
+			granted[scope] = true
+		}
+		for _, scope := range input.Scopes {
+			if !granted[scope] {
+				return nil, NewForbiddenError(fmt.Errorf("%w: scope %s is required", ErrInsufficientScope, scope)).
+					WithCode("insufficient_scope")
+			}
+		}
+		return claims, nil
+	}
+}
+
+// isBearerScheme returns whether the credentials of the security scheme are passed as bearer token.
+func isBearerScheme(scheme *openapi3.SecurityScheme) bool {
+	switch scheme.Type {
+	case "http":
+		return strings.EqualFold(scheme.Scheme, "bearer")
+	case "oauth2", "openIdConnect":
+		return true
+	default:
+		return false
+	}
+}
+
+// bearerToken extracts the token of the Authorization header with the bearer scheme.
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// verify checks the signature of a JSON Web Token in the compact serialization and returns its claims.
+func (keySet *KeySet) verify(token string) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is malformed")
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("header is malformed: %w", err)
+	}
+	key, err := keySet.lookup(header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.algorithm != "" && key.algorithm != header.Algorithm {
+		return nil, fmt.Errorf("key %q must not be used with algorithm %s", header.KeyID, header.Algorithm)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("signature is malformed: %w", err)
+	}
+	if err = verifySignature(header.Algorithm, key.key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+	var claims JWTClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("claims are malformed: %w", err)
+	}
+	return claims, nil
+}
+
+// lookup returns the key with the key ID. A token without key ID is accepted, if the KeySet contains a single key.
+func (keySet *KeySet) lookup(keyID string) (jsonWebKey, error) {
+	if key, ok := keySet.keys[keyID]; ok {
+		return key, nil
+	}
+	if keyID == "" && len(keySet.keys) == 1 {
+		for _, key := range keySet.keys {
+			return key, nil
+		}
+	}
+	return jsonWebKey{}, fmt.Errorf("unknown key %q", keyID)
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token.
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// signingAlgorithms maps the supported asymmetric JWS algorithms to their key type, hash function and, for ECDSA, the
+// curve of the key.
+var signingAlgorithms = map[string]struct {
+	keyType string
+	hash    crypto.Hash
+	curve   string
+}{
+	"RS256": {"RSA", crypto.SHA256, ""}, "RS384": {"RSA", crypto.SHA384, ""}, "RS512": {"RSA", crypto.SHA512, ""},
+	"PS256": {"RSA-PSS", crypto.SHA256, ""}, "PS384": {"RSA-PSS", crypto.SHA384, ""},
+	"PS512": {"RSA-PSS", crypto.SHA512, ""},
+	"ES256": {"EC", crypto.SHA256, "P-256"}, "ES384": {"EC", crypto.SHA384, "P-384"},
+	"ES512": {"EC", crypto.SHA512, "P-521"},
+	"EdDSA": {"OKP", 0, ""},
+}
+
+// verifySignature verifies the signature of the signing input with the algorithm of the token. The algorithm "none"
+// and symmetric algorithms are rejected, as well as ECDSA keys on another curve than the one of the algorithm.
+func verifySignature(algorithm string, key crypto.PublicKey, input []byte, signature []byte) error {
+	signingAlgorithm, ok := signingAlgorithms[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	var digest []byte
+	if signingAlgorithm.hash != 0 {
+		hasher := signingAlgorithm.hash.New()
+		hasher.Write(input)
+		digest = hasher.Sum(nil)
+	}
+	valid := false
+	switch typedKey := key.(type) {
+	case *rsa.PublicKey:
+		if signingAlgorithm.keyType == "RSA" {
+			valid = rsa.VerifyPKCS1v15(typedKey, signingAlgorithm.hash, digest, signature) == nil
+		} else if signingAlgorithm.keyType == "RSA-PSS" {
+			valid = rsa.VerifyPSS(typedKey, signingAlgorithm.hash, digest, signature,
+				&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (typedKey.Curve.Params().BitSize + 7) / 8
+		if signingAlgorithm.keyType == "EC" && typedKey.Curve.Params().Name == signingAlgorithm.curve &&
+			len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(typedKey, digest, r, s)
+		}
+	case ed25519.PublicKey:
+		valid = signingAlgorithm.keyType == "OKP" && ed25519.Verify(typedKey, input, signature)
+	}
+	if !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// validate checks the registered claims "exp", "nbf", "iss" and "aud" of a token. A token without "exp" claim is
+// rejected, because it would never expire.
+func (options JWTOptions) validate(claims JWTClaims) error {
+	now := options.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiration time")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(options.Leeway)) {
+		return errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(options.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token is not valid yet")
+	}
+	if options.Issuer != "" && claims["iss"] != options.Issuer {
+		return errors.New("token was issued by another issuer")
+	}
+	if options.Audience != "" && !hasAudience(claims["aud"], options.Audience) {
+		return errors.New("token was issued for another audience")
+	}
+	return nil
+}
+
+// hasAudience returns whether the "aud" claim, which is a string or an array of strings, contains the audience.
+func hasAudience(claim interface{}, audience string) bool {
+	switch aud := claim.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, value := range aud {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+package openapirouter
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const jwtTestSpec = `
+openapi: 3.0.3
+info:
+  title: JWT-API
+  version: 1.0.0
+paths:
+  /clients:
+    get:
+      operationId: getClients
+      security:
+        - oauth: [clients.read]
+      responses:
+        '204':
+          description: no content
+components:
+  securitySchemes:
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://example.com/token
+          scopes:
+            clients.read: read clients
+`
+
+var jwtTestTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func encodeSegment(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signRS256(key *rsa.PrivateKey, keyID string, claims map[string]interface{}) string {
+	input := encodeSegment(map[string]string{"alg": "RS256", "kid": keyID}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(input))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(key *ecdsa.PrivateKey, claims map[string]interface{}) string {
+	input := encodeSegment(map[string]string{"alg": "ES256"}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(input))
+	r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signEdDSA(key ed25519.PrivateKey, claims map[string]interface{}) string {
+	input := encodeSegment(map[string]string{"alg": "EdDSA"}) + "." + encodeSegment(claims)
+	return input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(input)))
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "client-1",
+		"iss":   "https://issuer.example.com",
+		"aud":   []string{"clients-api"},
+		"exp":   jwtTestTime.Add(time.Hour).Unix(),
+		"nbf":   jwtTestTime.Add(-time.Hour).Unix(),
+		"scope": "clients.read clients.write",
+	}
+}
+
+func getJWTRouterAndServer(key *rsa.PrivateKey) (*Router, *httptest.Server) {
+	keySet := NewKeySet()
+	if err := keySet.AddKey("key-1", &key.PublicKey); err != nil {
+		panic(err)
+	}
+	router, err := NewRouterFromData([]byte(jwtTestSpec), WithSecurityScheme("oauth", NewJWTAuthenticator(keySet,
+		JWTOptions{
+			Issuer:   "https://issuer.example.com",
+			Audience: "clients-api",
+			Now:      func() time.Time { return jwtTestTime },
+		})))
+	if err != nil {
+		panic(err)
+	}
+	return router, httptest.NewServer(router)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	testCases := []struct {
+		name     string
+		token    func() string
+		expected int
+	}{
+		{"valid token", func() string {
+			return signRS256(key, "key-1", validClaims())
+		}, http.StatusNoContent},
+		{"missing token", func() string {
+			return ""
+		}, http.StatusUnauthorized},
+		{"unknown key", func() string {
+			return signRS256(otherKey, "key-1", validClaims())
+		}, http.StatusUnauthorized},
+		{"expired token", func() string {
+			claims := validClaims()
+			claims["exp"] = jwtTestTime.Add(-time.Minute).Unix()
+			return signRS256(key, "key-1", claims)
+		}, http.StatusUnauthorized},
+		{"token without expiration", func() string {
+			claims := validClaims()
+			delete(claims, "exp")
+			return signRS256(key, "key-1", claims)
+		}, http.StatusUnauthorized},
+		{"token not valid yet", func() string {
+			claims := validClaims()
+			claims["nbf"] = jwtTestTime.Add(time.Minute).Unix()
+			return signRS256(key, "key-1", claims)
+		}, http.StatusUnauthorized},
+		{"other issuer", func() string {
+			claims := validClaims()
+			claims["iss"] = "https://other.example.com"
+			return signRS256(key, "key-1", claims)
+		}, http.StatusUnauthorized},
+		{"other audience", func() string {
+			claims := validClaims()
+			claims["aud"] = "other-api"
+			return signRS256(key, "key-1", claims)
+		}, http.StatusUnauthorized},
+		{"algorithm none", func() string {
+			return encodeSegment(map[string]string{"alg": "none", "kid": "key-1"}) + "." +
+				encodeSegment(validClaims()) + "."
+		}, http.StatusUnauthorized},
+		{"insufficient scope", func() string {
+			claims := validClaims()
+			claims["scope"] = "clients.write"
+			return signRS256(key, "key-1", claims)
+		}, http.StatusForbidden},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			router, server := getJWTRouterAndServer(key)
+			defer server.Close()
+			router.HandleOperation("getClients", handleNoContent)
+			request, _ := http.NewRequest(http.MethodGet, server.URL+"/clients", nil)
+			if token := testCase.token(); token != "" {
+				request.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			// when
+			res, err := server.Client().Do(request)
+
+			// then
+			assert.Nil(t, err)
+			if assert.NotNil(t, res) {
+				assert.Equal(t, testCase.expected, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator_ShouldReturnClaims(t *testing.T) {
+	// given
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	router, server := getJWTRouterAndServer(key)
+	defer server.Close()
+	var claims JWTClaims
+	router.HandleOperation("getClients", func(request *http.Request, _ map[string]string) (*Response, error) {
+		claims, _ = Principal[JWTClaims](request)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/clients", nil)
+	request.Header.Set("Authorization", "Bearer "+signRS256(key, "key-1", validClaims()))
+
+	// when
+	res, err := server.Client().Do(request)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	assert.Equal(t, "client-1", claims.Subject())
+	assert.Equal(t, []string{"clients.read", "clients.write"}, claims.Scopes())
+}
+
+func TestKeySet_ShouldVerifyAlgorithms(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublicKey, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	testCases := []struct {
+		name      string
+		publicKey crypto.PublicKey
+		token     string
+	}{
+		{"RS256", &rsaKey.PublicKey, signRS256(rsaKey, "", validClaims())},
+		{"ES256", &ecKey.PublicKey, signES256(ecKey, validClaims())},
+		{"EdDSA", edPublicKey, signEdDSA(edKey, validClaims())},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			keySet := NewKeySet()
+			assert.Nil(t, keySet.AddKey("", testCase.publicKey))
+
+			// when
+			claims, err := keySet.verify(testCase.token)
+
+			// then
+			assert.Nil(t, err)
+			assert.Equal(t, "client-1", claims.Subject())
+		})
+	}
+}
+
+func TestKeySet_ShouldRejectAlgorithmOfOtherKeyType(t *testing.T) {
+	// given
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keySet := NewKeySet()
+	assert.Nil(t, keySet.AddKey("", &rsaKey.PublicKey))
+
+	// when
+	_, err := keySet.verify(signES256(ecKey, validClaims()))
+
+	// then
+	assert.NotNil(t, err)
+}
+
+func TestKeySet_ShouldRejectKeyOnOtherCurve(t *testing.T) {
+	// given
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	keySet := NewKeySet()
+	assert.Nil(t, keySet.AddKey("", &ecKey.PublicKey))
+	input := encodeSegment(map[string]string{"alg": "ES256"}) + "." + encodeSegment(validClaims())
+	digest := sha256.Sum256([]byte(input))
+	r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+	signature := make([]byte, 96)
+	r.FillBytes(signature[:48])
+	s.FillBytes(signature[48:])
+
+	// when
+	_, err := keySet.verify(input + "." + base64.RawURLEncoding.EncodeToString(signature))
+
+	// then
+	assert.EqualError(t, err, "invalid signature")
+}
+
+func TestLoadJWKS(t *testing.T) {
+	// given
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	encode := func(value []byte) string {
+		return base64.RawURLEncoding.EncodeToString(value)
+	}
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]interface{}{
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256", "use": "sig", "n": encode(rsaKey.N.Bytes()),
+			"e": encode(big.NewInt(int64(rsaKey.E)).Bytes()), "key_ops": []string{"verify"},
+			"x5c": []string{"MIIC+DCCAeCgAwIBAgIJBIGjYW6hFpn2MA0GCSqGSIb3DQEBBQUAMCMxITAfBgNVBAMTGGN1c3RvbWVyLWRlbW9zLmF1dGgwLmNvbQ"}},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.Bytes()), "y": encode(ecKey.Y.Bytes())},
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": encode(edPublicKey)},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "", "e": ""},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, jwks, 0600))
+
+	// when
+	keySet, err := LoadJWKS(path)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, keySet) {
+		assert.Len(t, keySet.keys, 3)
+		assert.Equal(t, &rsaKey.PublicKey, keySet.keys["rsa"].key)
+		assert.Equal(t, "RS256", keySet.keys["rsa"].algorithm)
+		assert.True(t, ecKey.PublicKey.Equal(keySet.keys["ec"].key))
+		assert.Equal(t, edPublicKey, keySet.keys["ed"].key)
+		_, err = keySet.verify(signRS256(rsaKey, "rsa", validClaims()))
+		assert.Nil(t, err)
+	}
+}
+
+func TestParseJWKS_InvalidKey(t *testing.T) {
+	// when
+	_, err := ParseJWKS([]byte(`{"keys": [{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`))
+
+	// then
+	assert.NotNil(t, err)
+}
+
+func TestJWTAuthenticator_ShouldChallengeInsufficientScope(t *testing.T) {
+	// given
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	router, server := getJWTRouterAndServer(key)
+	defer server.Close()
+	router.HandleOperation("getClients", handleNoContent)
+	claims := validClaims()
+	delete(claims, "scope")
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/clients", nil)
+	request.Header.Set("Authorization", "Bearer "+signRS256(key, "key-1", claims))
+
+	// when
+	res, err := server.Client().Do(request)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		assert.Equal(t, `Bearer realm="JWT-API", scope="clients.read", error="insufficient_scope"`,
+			res.Header.Get("WWW-Authenticate"))
+	}
+}
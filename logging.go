@@ -0,0 +1,48 @@
+package openapirouter
+
+import (
+	"github.com/getkin/kin-openapi/routers"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithAccessLog writes an access log entry with the level to the slog.Logger of the Router for every request. The
+// entry contains the method, the path, the operationId of the matched operation, the status code, the size of the
+// response body and the duration of the request. Requests rejected by the Router additionally contain the reason of
+// the rejection and the error causing it, e.g. the validation error of an invalid request.
+func WithAccessLog(level slog.Level) Option {
+	return func(router *Router) {
+		router.accessLog = &level
+	}
+}
+
+// logAccess wraps the handler of a request to write its access log entry. The route is nil for requests without a
+// matching route.
+func (router *Router) logAccess(handler http.Handler, route *routers.Route) http.Handler {
+	operationID := ""
+	if route != nil {
+		operationID = route.Operation.OperationID
+	}
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		request, observation := observe(request)
+		recorder := NewStatusRecorder(writer)
+		handler.ServeHTTP(recorder, request)
+		attributes := []slog.Attr{
+			slog.String("method", request.Method),
+			slog.String("path", request.URL.Path),
+			slog.String("operationId", operationID),
+			slog.Int("status", recorder.Status()),
+			slog.Int("size", recorder.Size()),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if observation.rejection != "" {
+			attributes = append(attributes, slog.String("rejection", observation.rejection))
+		}
+		if observation.err != nil {
+			attributes = append(attributes, slog.String("error", observation.err.Error()))
+		}
+		router.logger.LogAttrs(request.Context(), *router.accessLog, "Request served", attributes...)
+	})
+}
@@ -0,0 +1,179 @@
+package openapirouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getLoggingRouter(opts ...Option) (*Router, *bytes.Buffer) {
+	var output bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	router, err := NewRouter("testdata/test-api.yaml", append([]Option{WithLogger(logger)}, opts...)...)
+	if err != nil {
+		panic(err)
+	}
+	return router, &output
+}
+
+func logEntries(output *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	decoder := json.NewDecoder(output)
+	for decoder.More() {
+		var entry map[string]interface{}
+		if err := decoder.Decode(&entry); err != nil {
+			panic(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestWithAccessLog_ShouldLogRequests(t *testing.T) {
+	// given
+	router, output := getLoggingRouter(WithAccessLog(slog.LevelDebug))
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{StatusCode: http.StatusOK, Body: TestData{Data: "test"}}, nil
+	})
+
+	// when
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	// then
+	entries := logEntries(output)
+	if assert.Len(t, entries, 1) {
+		entry := entries[0]
+		assert.Equal(t, "DEBUG", entry["level"])
+		assert.Equal(t, "Request served", entry["msg"])
+		assert.Equal(t, "GET", entry["method"])
+		assert.Equal(t, "/test", entry["path"])
+		assert.Equal(t, "getTestData", entry["operationId"])
+		assert.Equal(t, float64(http.StatusOK), entry["status"])
+		assert.Equal(t, float64(len(`{"data":"test"}`+"\n")), entry["size"])
+		assert.Contains(t, entry, "duration")
+		assert.NotContains(t, entry, "rejection")
+		assert.NotContains(t, entry, "error")
+	}
+}
+
+func TestWithAccessLog_ShouldLogValidationErrors(t *testing.T) {
+	// given
+	router, output := getLoggingRouter(WithAccessLog(slog.LevelInfo))
+	router.HandleOperation("getQuery", handleNoContent)
+
+	// when
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/query", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	// then
+	entries := logEntries(output)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "getQuery", entries[0]["operationId"])
+		assert.Equal(t, float64(http.StatusBadRequest), entries[0]["status"])
+		assert.Equal(t, RejectionInvalidRequest, entries[0]["rejection"])
+		assert.Contains(t, entries[0]["error"], "query")
+		assert.Equal(t, "", entries[1]["operationId"])
+		assert.Equal(t, float64(http.StatusNotFound), entries[1]["status"])
+		assert.Equal(t, RejectionNotFound, entries[1]["rejection"])
+		assert.NotEmpty(t, entries[1]["error"])
+	}
+}
+
+func TestLogger_ShouldLogHandlerErrors(t *testing.T) {
+	// given
+	router, output := getLoggingRouter()
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, errors.New("database unavailable")
+	})
+	router.HandleOperation("postTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, fmt.Errorf("client 42: %w", NewHTTPError(http.StatusNotFound))
+	})
+
+	// when
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(`{"data":"test"}`))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	// then
+	entries := logEntries(output)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "ERROR", entries[0]["level"])
+		assert.Equal(t, "Request handler failed", entries[0]["msg"])
+		assert.Equal(t, "getTestData", entries[0]["operationId"])
+		assert.Equal(t, float64(http.StatusInternalServerError), entries[0]["status"])
+		assert.Equal(t, "database unavailable", entries[0]["error"])
+		assert.Equal(t, "ERROR", entries[1]["level"])
+		assert.Equal(t, "postTestData", entries[1]["operationId"])
+		assert.Equal(t, float64(http.StatusNotFound), entries[1]["status"])
+		assert.Equal(t, "client 42: Not found", entries[1]["error"])
+	}
+}
+
+func TestLogger_ShouldLogInvalidConfiguration(t *testing.T) {
+	// given
+	router, output := getLoggingRouter()
+
+	// when
+	handle := func() { router.HandleOperation("unknownOperation", handleNoContent) }
+
+	// then
+	assert.PanicsWithError(t, "no operation with operationId unknownOperation is specified", handle)
+	entries := logEntries(output)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "ERROR", entries[0]["level"])
+		assert.Equal(t, "Invalid router configuration", entries[0]["msg"])
+		assert.Equal(t, "no operation with operationId unknownOperation is specified", entries[0]["error"])
+	}
+}
+
+type requestIDKey struct{}
+
+// requestIDHandler adds the request ID of the context to every log entry.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (handler requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+		record.AddAttrs(slog.String("requestId", requestID))
+	}
+	return handler.Handler.Handle(ctx, record)
+}
+
+// failingWriter is an http.ResponseWriter whose body cannot be written.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write(_ []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestLogger_ShouldLogWriteErrorsWithRequestContext(t *testing.T) {
+	// given
+	var output bytes.Buffer
+	logger := slog.New(requestIDHandler{Handler: slog.NewJSONHandler(&output, nil)})
+	router, err := NewRouter("testdata/test-api.yaml", WithLogger(logger))
+	assert.Nil(t, err)
+	request := httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	request = request.WithContext(context.WithValue(request.Context(), requestIDKey{}, "42"))
+
+	// when
+	router.ServeHTTP(failingWriter{httptest.NewRecorder()}, request)
+
+	// then
+	entries := logEntries(&output)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "Could not write response", entries[0]["msg"])
+		assert.Equal(t, "connection reset", entries[0]["error"])
+		assert.Equal(t, "42", entries[0]["requestId"])
+	}
+}
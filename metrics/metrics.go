@@ -0,0 +1,112 @@
+// Package metrics records Prometheus metrics for the requests served by an openapirouter.Router.
+package metrics
+
+import (
+	"github.com/huk-coburg/openapirouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics records Prometheus metrics for the requests served by a Router. The metrics are labeled by the operationId,
+// the path template and the method of the matched operation. For requests without a matching route, these labels are
+// empty. Metrics implements prometheus.Collector, so it can be registered with any prometheus.Registerer. It is added
+// to a Router with the WithMetrics option. The following metrics are recorded:
+//   - requests_total: counter of the handled requests by status code
+//   - request_duration_seconds: histogram of the latency of the requests by status code
+//   - requests_in_flight: gauge of the requests currently handled
+//   - response_size_bytes: histogram of the size of the response bodies by status code
+//   - rejected_requests_total: counter of the requests rejected by the Router by the reason, which is one of
+//     invalid_request, unauthorized, forbidden, not_found, method_not_allowed, not_implemented, not_acceptable,
+//     payload_too_large and internal_error
+type Metrics struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inFlight     *prometheus.GaugeVec
+	responseSize *prometheus.HistogramVec
+	rejections   *prometheus.CounterVec
+}
+
+// NewMetrics creates the Metrics with the namespace prepended to the names of all metrics, e.g. "clients_api". The
+// namespace may be empty.
+func NewMetrics(namespace string) *Metrics {
+	routeLabels := []string{"operation", "path", "method"}
+	statusLabels := []string{"operation", "path", "method", "status"}
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Total number of handled requests.",
+		}, statusLabels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the handled requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, statusLabels),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Number of requests currently handled.",
+		}, routeLabels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "response_size_bytes",
+			Help:      "Size of the response bodies.",
+			Buckets:   prometheus.ExponentialBuckets(100, 10, 6),
+		}, statusLabels),
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rejected_requests_total",
+			Help:      "Total number of requests rejected by the router.",
+		}, []string{"operation", "path", "method", "reason"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (metrics *Metrics) Describe(descriptions chan<- *prometheus.Desc) {
+	metrics.requests.Describe(descriptions)
+	metrics.duration.Describe(descriptions)
+	metrics.inFlight.Describe(descriptions)
+	metrics.responseSize.Describe(descriptions)
+	metrics.rejections.Describe(descriptions)
+}
+
+// Collect implements prometheus.Collector.
+func (metrics *Metrics) Collect(collected chan<- prometheus.Metric) {
+	metrics.requests.Collect(collected)
+	metrics.duration.Collect(collected)
+	metrics.inFlight.Collect(collected)
+	metrics.responseSize.Collect(collected)
+	metrics.rejections.Collect(collected)
+}
+
+// instrument wraps the handling of a request to record its metrics.
+func (metrics *Metrics) instrument(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		labels := prometheus.Labels{"operation": "", "path": "", "method": ""}
+		if info, ok := openapirouter.RouteInfoFromContext(request.Context()); ok {
+			labels = prometheus.Labels{"operation": info.OperationID, "path": info.PathTemplate, "method": info.Method}
+		}
+		inFlight := metrics.inFlight.With(labels)
+		inFlight.Inc()
+		defer inFlight.Dec()
+		recorder := openapirouter.NewStatusRecorder(writer)
+		handler.ServeHTTP(recorder, request)
+		if reason, _ := openapirouter.RejectionFromContext(request.Context()); reason != "" {
+			metrics.rejections.MustCurryWith(labels).WithLabelValues(reason).Inc()
+		}
+		statusLabels := prometheus.Labels{"status": strconv.Itoa(recorder.Status())}
+		metrics.requests.MustCurryWith(labels).With(statusLabels).Inc()
+		metrics.duration.MustCurryWith(labels).With(statusLabels).Observe(time.Since(start).Seconds())
+		metrics.responseSize.MustCurryWith(labels).With(statusLabels).Observe(float64(recorder.Size()))
+	})
+}
+
+// WithMetrics records Prometheus metrics for all requests served by the Router. The Metrics have to be registered
+// with a prometheus.Registerer to be exposed.
+func WithMetrics(metrics *Metrics) openapirouter.Option {
+	return openapirouter.WithInstrumentation(metrics.instrument)
+}
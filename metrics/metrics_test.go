@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/huk-coburg/openapirouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func handleNoContent(_ *http.Request, _ map[string]string) (*openapirouter.Response, error) {
+	return &openapirouter.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+func getMetricsRouterAndServer() (*openapirouter.Router, *httptest.Server, *Metrics) {
+	metrics := NewMetrics("test")
+	router, err := openapirouter.NewRouter("../testdata/test-api.yaml", WithMetrics(metrics))
+	if err != nil {
+		panic(err)
+	}
+	return router, httptest.NewServer(router), metrics
+}
+
+func TestMetrics_ShouldRecordRequests(t *testing.T) {
+	// given
+	router, server, metrics := getMetricsRouterAndServer()
+	defer server.Close()
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(metrics))
+	router.HandleOperation("getPathParams", func(_ *http.Request, _ map[string]string) (
+		*openapirouter.Response, error) {
+		return &openapirouter.Response{StatusCode: http.StatusOK, Body: map[string]string{"data": "test"}}, nil
+	})
+
+	// when
+	for _, path := range []string{"/test/pathParams/value1", "/test/pathParams/value2"} {
+		res, err := server.Client().Get(server.URL + path)
+		assert.Nil(t, err)
+		if assert.NotNil(t, res) {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+		}
+	}
+
+	// then
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP test_requests_total Total number of handled requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",operation="getPathParams",path="/test/pathParams/{param}",status="200"} 2
+`), "test_requests_total"))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.inFlight.WithLabelValues("getPathParams",
+		"/test/pathParams/{param}", "GET")))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.duration))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.responseSize))
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.rejections))
+	lint, err := testutil.CollectAndLint(metrics)
+	assert.Nil(t, err)
+	assert.Empty(t, lint)
+}
+
+func TestMetrics_ShouldRecordRejections(t *testing.T) {
+	// given
+	router, server, metrics := getMetricsRouterAndServer()
+	defer server.Close()
+	router.HandleOperation("getQuery", handleNoContent)
+	router.HandleOperation("getSecured", handleNoContent)
+
+	// when
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/test/query"},
+		{http.MethodGet, "/test/secured"},
+		{http.MethodGet, "/unknown"},
+		{http.MethodDelete, "/test"},
+		{http.MethodGet, "/test"},
+	}
+	for _, request := range requests {
+		req, _ := http.NewRequest(request.method, server.URL+request.path, nil)
+		res, err := server.Client().Do(req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	}
+
+	// then
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.rejections.WithLabelValues("getQuery", "/test/query",
+		"GET", "invalid_request")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.rejections.WithLabelValues("getSecured", "/test/secured",
+		"GET", "internal_error")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.rejections.WithLabelValues("", "", "",
+		"not_found")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.rejections.WithLabelValues("", "", "",
+		"method_not_allowed")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.rejections.WithLabelValues("getTestData", "/test",
+		"GET", "not_implemented")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("getQuery", "/test/query",
+		"GET", "400")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("", "", "", "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("", "", "", "405")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("getTestData", "/test",
+		"GET", "501")))
+}
+
+func TestMetrics_ShouldRecordAuthenticationFailures(t *testing.T) {
+	// given
+	router, server, metrics := getMetricsRouterAndServer()
+	defer server.Close()
+	router.HandleOperation("getSecured", handleNoContent)
+	router.RegisterSecurityScheme("apiKey", func(_ context.Context, input *openapi3filter.AuthenticationInput) (
+		interface{}, error) {
+		if input.RequestValidationInput.Request.Header.Get("x-api-key") == "" {
+			return nil, openapirouter.NewUnauthenticatedError(errors.New("missing api key"))
+		}
+		return nil, openapirouter.NewForbiddenError(nil)
+	})
+
+	// when
+	for _, apiKey := range []string{"", "key"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/test/secured", nil)
+		if apiKey != "" {
+			req.Header.Set("x-api-key", apiKey)
+		}
+		res, err := server.Client().Do(req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	}
+
+	// then
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.rejections.WithLabelValues("getSecured", "/test/secured",
+		"GET", "unauthorized")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.rejections.WithLabelValues("getSecured", "/test/secured",
+		"GET", "forbidden")))
+}
@@ -0,0 +1,37 @@
+package openapirouter
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Middleware wraps an http.Handler to add behavior before or after the handling of a request, e.g. logging, metrics
+// or policy decisions. The route of the OpenAPI specification matched by the request is available by RouteFromContext.
+type Middleware func(http.Handler) http.Handler
+
+// Use adds middleware wrapping the handling of every request by the Router. It is invoked after the route was matched,
+// but before the request is validated, so it also observes invalid and unauthorized requests. Requests without a
+// matching route pass the middleware as well, but without a route in their context. The middleware added first is the
+// outermost one.
+func (router *Router) Use(middlewares ...Middleware) {
+	router.middlewares = append(router.middlewares, middlewares...)
+}
+
+// UseForOperation adds middleware wrapping the handling of the requests for the operation with the specified
+// operationId. It is invoked inside the middleware added with Use and before the request is validated. The function
+// panics, if no operation with the operationId is specified in the OpenAPI specification.
+func (router *Router) UseForOperation(operationID string, middlewares ...Middleware) {
+	operation, ok := router.operations[operationID]
+	if !ok {
+		router.fail(fmt.Errorf("no operation with operationId %s is specified", operationID))
+	}
+	router.operationMiddlewares[operation] = append(router.operationMiddlewares[operation], middlewares...)
+}
+
+// chain wraps the handler with the middleware, so the first middleware is the outermost one.
+func chain(handler http.Handler, middlewares []Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,128 @@
+package openapirouter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			operationID := ""
+			if route, ok := RouteFromContext(request.Context()); ok {
+				operationID = route.Operation.OperationID
+			}
+			*calls = append(*calls, name+":"+operationID)
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+func TestUse_ShouldWrapHandlersInOrder(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	var calls []string
+	router.Use(recordingMiddleware("first", &calls), recordingMiddleware("second", &calls))
+	router.UseForOperation("getTestData", recordingMiddleware("operation", &calls))
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		calls = append(calls, "handler")
+		return &Response{StatusCode: http.StatusOK, Body: TestData{Data: "test"}}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	assert.Equal(t, []string{"first:getTestData", "second:getTestData", "operation:getTestData", "handler"}, calls)
+}
+
+func TestUse_ShouldObserveInvalidRequests(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	var calls []string
+	var status int
+	router.Use(recordingMiddleware("router", &calls), func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			buffer := newResponseBuffer()
+			next.ServeHTTP(buffer, request)
+			status = buffer.statusCode
+			_ = buffer.writeTo(writer)
+		})
+	})
+	router.UseForOperation("getQuery", recordingMiddleware("operation", &calls))
+	router.HandleOperation("getQuery", handleNoContent)
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test/query")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	}
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []string{"router:getQuery", "operation:getQuery"}, calls)
+}
+
+func TestUse_ShouldPassUnmatchedRequestsWithoutRoute(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	var calls []string
+	router.Use(recordingMiddleware("router", &calls))
+
+	// when
+	res, err := server.Client().Get(server.URL + "/unknown")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	}
+	assert.Equal(t, []string{"router:"}, calls)
+}
+
+func TestUse_ShouldShortCircuit(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	called := false
+	router.UseForOperation("getTestData", func(_ http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusTeapot)
+		})
+	})
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		called = true
+		return &Response{StatusCode: http.StatusOK}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	assert.False(t, called)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	}
+}
+
+func TestUseForOperation_UnknownOperation(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+
+	// when
+	use := func() { router.UseForOperation("unknownOperation", recordingMiddleware("operation", nil)) }
+
+	// then
+	assert.Panics(t, use)
+}
@@ -0,0 +1,156 @@
+package openapirouter
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"log/slog"
+	"net/http"
+)
+
+// Option configures the router-wide behavior of a Router. Options are passed to the constructors of the Router, e.g.
+// NewRouter.
+type Option func(*Router)
+
+// WithValidationOptions sets the default openapi3filter.Options used to validate the requests of every endpoint. They
+// are merged into the current options: MultiError stays enabled to report all violations of a request, and an
+// AuthenticationFunc set before, e.g. with WithAuthFunc, is kept, unless the options contain another one. An
+// openapi3filter.AuthenticationFunc added with a request handler overrides the AuthenticationFunc of the options.
+func WithValidationOptions(options openapi3filter.Options) Option {
+	return func(router *Router) {
+		options.MultiError = options.MultiError || router.options.MultiError
+		if options.AuthenticationFunc == nil {
+			options.AuthenticationFunc = router.options.AuthenticationFunc
+		}
+		router.options = options
+	}
+}
+
+// WithAuthFunc sets the default openapi3filter.AuthenticationFunc used for every endpoint with SecurityRequirements,
+// so it does not need to be added with every request handler.
+func WithAuthFunc(authFunc openapi3filter.AuthenticationFunc) Option {
+	return func(router *Router) {
+		router.options.AuthenticationFunc = authFunc
+	}
+}
+
+// WithLogger sets the slog.Logger the Router uses to report errors and to write the access log. By default, the
+// default logger of the slog package is used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(router *Router) {
+		router.logger = logger
+	}
+}
+
+// WithErrorMapping adds a custom error that should be mapped to an error response, like Router.AddErrorMapping does.
+func WithErrorMapping(err error, responseCode int, details ...string) Option {
+	return func(router *Router) {
+		router.errMapper.addTypeMapping(err, NewHTTPError(responseCode, details...))
+	}
+}
+
+// WithSentinelErrorMapping adds a sentinel error value that should be mapped to an error response, like
+// Router.AddSentinelErrorMapping does.
+func WithSentinelErrorMapping(sentinel error, responseCode int, details ...string) Option {
+	return func(router *Router) {
+		router.errMapper.addSentinelMapping(sentinel, NewHTTPError(responseCode, details...))
+	}
+}
+
+// WithErrorMapper adds a function to map errors to a response, like Router.AddErrorMapper does.
+func WithErrorMapper(mapper func(error) (*Response, bool)) Option {
+	return func(router *Router) {
+		router.errMapper.addMapper(mapper)
+	}
+}
+
+// WithErrorFallback sets the function that creates the response for all errors returned by a HandleRequestFunction,
+// which are not mapped by any error mapping. By default, they are mapped to an Internal Server Error.
+func WithErrorFallback(fallback func(error) *Response) Option {
+	return func(router *Router) {
+		router.errMapper.fallback = fallback
+	}
+}
+
+// WithNotFoundHandler sets the http.Handler that is invoked for requests which do not match any path of the OpenAPI
+// specification, instead of responding with http.StatusNotFound.
+func WithNotFoundHandler(handler http.Handler) Option {
+	return func(router *Router) {
+		router.notFoundHandler = handler
+	}
+}
+
+// WithMethodNotAllowedHandler sets the http.Handler that is invoked for requests which match a path of the OpenAPI
+// specification, but none of its methods, instead of responding with http.StatusMethodNotAllowed.
+func WithMethodNotAllowedHandler(handler http.Handler) Option {
+	return func(router *Router) {
+		router.methodNotAllowedHandler = handler
+	}
+}
+
+// WithResponseValidation sets the ResponseValidationMode of the Router, like Router.SetResponseValidationMode does.
+func WithResponseValidation(mode ResponseValidationMode) Option {
+	return func(router *Router) {
+		router.responseValidation = mode
+	}
+}
+
+// WithTestMode enables the validation of responses in the ResponseValidationTest mode. It is meant to be passed to the
+// Router by the setup of tests, so the same Router validates its responses in tests only.
+func WithTestMode() Option {
+	return func(router *Router) {
+		router.testMode = true
+	}
+}
+
+// WithSpecValidation validates the OpenAPI specification when the Router is created. The creation fails with the
+// validation error if the specification is invalid. The strictness of the validation is configured by the
+// openapi3.ValidationOption, e.g. openapi3.DisableExamplesValidation.
+func WithSpecValidation(options ...openapi3.ValidationOption) Option {
+	return func(router *Router) {
+		router.validateSpec = true
+		router.specValidation = options
+	}
+}
+
+// WithServerURLs replaces the servers of the OpenAPI specification which are used to match the incoming requests. This
+// is useful if the service is not reachable under the URLs documented in the specification, e.g. behind a proxy. If no
+// URL is passed, the servers are ignored and the paths of the specification are matched without any prefix.
+func WithServerURLs(serverURLs ...string) Option {
+	return func(router *Router) {
+		router.overrideServers = true
+		router.serverURLs = serverURLs
+	}
+}
+
+// WithProblemDetails writes all error responses of the Router as problem details document according to RFC 9457
+// (formerly RFC 7807) with the media type application/problem+json. The type member of the problem is built from the
+// baseTypeURI and the status code, e.g. "https://example.com/problems/404". If the baseTypeURI is empty, the type is
+// set to "about:blank". A Type set explicitly on an HTTPError is kept.
+func WithProblemDetails(baseTypeURI string) Option {
+	return func(router *Router) {
+		router.errMapper.problemDetails = true
+		router.errMapper.problemTypeURI = baseTypeURI
+	}
+}
+
+// WithSecurityScheme registers the Authenticator for a security scheme of the OpenAPI specification, like
+// Router.RegisterSecurityScheme does. The creation of the Router fails, if the security scheme is not specified.
+func WithSecurityScheme(name string, authenticator Authenticator) Option {
+	return func(router *Router) {
+		router.authenticators[name] = authenticator
+	}
+}
+
+// WithBodyDecoder registers the openapi3filter.BodyDecoder for a media type, like Router.RegisterBodyDecoder does.
+func WithBodyDecoder(mediaType string, decoder openapi3filter.BodyDecoder) Option {
+	return func(router *Router) {
+		router.decoders.register(mediaType, decoder)
+	}
+}
+
+// WithEncoder registers the Encoder for a media type, like Router.RegisterEncoder does.
+func WithEncoder(mediaType string, encoder Encoder) Option {
+	return func(router *Router) {
+		router.encoders.register(mediaType, encoder)
+	}
+}
@@ -0,0 +1,326 @@
+package openapirouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getRouterAndServerWithOptions(path string, opts ...Option) (*Router, *httptest.Server) {
+	result, err := NewRouter(path, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return result, httptest.NewServer(result)
+}
+
+func TestOptions_WithAuthFunc(t *testing.T) {
+	// given
+	router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithAuthFunc(openapi3filter.NoopAuthenticationFunc))
+	defer server.Close()
+	router.AddRequestHandler("GET", "/test/secured", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test/secured")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestOptions_WithValidationOptions(t *testing.T) {
+	// given
+	router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithValidationOptions(openapi3filter.Options{ExcludeRequestBody: true}))
+	defer server.Close()
+	called := false
+	router.AddRequestHandler("POST", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		called = true
+		return &Response{
+			StatusCode: http.StatusNoContent,
+		}, nil
+	})
+	dataBytes, _ := json.Marshal(&InvalidData{Invalid: "test"})
+
+	// when
+	res, err := server.Client().Post(server.URL+"/test", "application/json", bytes.NewReader(dataBytes))
+
+	// then
+	assert.Nil(t, err)
+	assert.True(t, called)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+}
+
+func TestOptions_WithValidationOptions_ShouldKeepDefaults(t *testing.T) {
+	// given
+	router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithAuthFunc(openapi3filter.NoopAuthenticationFunc),
+		WithValidationOptions(openapi3filter.Options{ExcludeRequestBody: true}))
+	defer server.Close()
+	router.AddRequestHandler("GET", "/test/secured", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test/secured")
+
+	// then
+	assert.Nil(t, err)
+	assert.True(t, router.options.MultiError)
+	assert.True(t, router.options.ExcludeRequestBody)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestOptions_WithNotFoundHandler(t *testing.T) {
+	// given
+	_, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithNotFoundHandler(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusTeapot)
+		})))
+	defer server.Close()
+
+	// when
+	res, err := server.Client().Get(server.URL + "/invalid")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	}
+}
+
+func TestOptions_WithMethodNotAllowedHandler(t *testing.T) {
+	// given
+	_, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithMethodNotAllowedHandler(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusTeapot)
+		})))
+	defer server.Close()
+
+	// when
+	res, err := server.Client().Post(server.URL+"/test/query", "application/json", nil)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	}
+}
+
+func TestOptions_WithErrorMapping(t *testing.T) {
+	// given
+	router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithErrorMapping(&ExampleError{}, http.StatusBadGateway))
+	defer server.Close()
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, &ExampleError{}
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	}
+}
+
+func TestOptions_WithLoggerAndResponseValidation(t *testing.T) {
+	// given
+	var output bytes.Buffer
+	router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithLogger(slog.New(slog.NewTextHandler(&output, nil))), WithResponseValidation(ResponseValidationLog))
+	defer server.Close()
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       InvalidData{Invalid: "test"},
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	assert.Contains(t, output.String(), "Response does not match specification")
+	assert.Contains(t, output.String(), "operationId=getTestData")
+}
+
+func TestOptions_WithSpecValidation(t *testing.T) {
+	// given
+	data := []byte(`
+openapi: 3.0.3
+info:
+  title: Invalid-API
+  version: 1.0.0
+paths:
+  /test:
+    get:
+      responses:
+        200:
+          content:
+            application/json:
+              schema:
+                type: string
+`)
+
+	// when
+	withoutValidation, withoutValidationErr := NewRouterFromData(data)
+	withValidation, withValidationErr := NewRouterFromData(data, WithSpecValidation())
+
+	// then
+	assert.Nil(t, withoutValidationErr)
+	assert.NotNil(t, withoutValidation)
+	assert.NotNil(t, withValidationErr)
+	assert.Nil(t, withValidation)
+}
+
+func TestOptions_WithServerURLs(t *testing.T) {
+	// given
+	router, server := getRouterAndServerWithOptions("testdata/test-api-servers.yaml", WithServerURLs("/prefix"))
+	defer server.Close()
+	router.HandleOperation("getClientData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+
+	// when
+	res1, err1 := server.Client().Get(server.URL + "/prefix/clients/test")
+	res2, err2 := server.Client().Get(server.URL + "/api/v1/clients/test")
+
+	// then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	if assert.NotNil(t, res1) && assert.NotNil(t, res2) {
+		assert.Equal(t, http.StatusOK, res1.StatusCode)
+		assert.Equal(t, http.StatusNotFound, res2.StatusCode)
+	}
+}
+
+func TestOptions_WithServerURLs_IgnoreServers(t *testing.T) {
+	// given
+	router, server := getRouterAndServerWithOptions("testdata/test-api-servers.yaml", WithServerURLs())
+	defer server.Close()
+	router.HandleOperation("getClientData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/clients/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestOptions_WithProblemDetails(t *testing.T) {
+	// given
+	router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithProblemDetails("https://example.com/problems/"))
+	defer server.Close()
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, NewHTTPError(http.StatusConflict, "already exists").WithExtension("id", 42)
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+		var problem map[string]interface{}
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&problem))
+		assert.Equal(t, map[string]interface{}{
+			"type":   "https://example.com/problems/409",
+			"title":  "Conflict",
+			"status": float64(http.StatusConflict),
+			"detail": "already exists",
+			"id":     float64(42),
+		}, problem)
+	}
+}
+
+func TestOptions_WithProblemDetails_RouterErrors(t *testing.T) {
+	// given
+	_, server := getRouterAndServerWithOptions("testdata/test-api.yaml", WithProblemDetails(""))
+	defer server.Close()
+
+	// when
+	res, err := server.Client().Get(server.URL + "/invalid")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+		var problem ProblemDetails
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&problem))
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, http.StatusNotFound, problem.Status)
+	}
+}
+
+func TestOptions_WithErrorMapperAndFallback(t *testing.T) {
+	// given
+	router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithErrorMapper(func(err error) (*Response, bool) {
+			var example *ExampleError
+			if !errors.As(err, &example) {
+				return nil, false
+			}
+			return &Response{StatusCode: http.StatusBadGateway}, true
+		}),
+		WithErrorFallback(func(err error) *Response {
+			return &Response{StatusCode: http.StatusServiceUnavailable}
+		}))
+	defer server.Close()
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, &ExampleError{}
+	})
+	router.AddRequestHandler("POST", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, errors.New("unknown")
+	})
+
+	// when
+	mappedRes, mappedErr := server.Client().Get(server.URL + "/test")
+	fallbackRes, fallbackErr := server.Client().Post(server.URL+"/test", "application/json", nil)
+
+	// then
+	assert.Nil(t, mappedErr)
+	assert.Nil(t, fallbackErr)
+	if assert.NotNil(t, mappedRes) && assert.NotNil(t, fallbackRes) {
+		assert.Equal(t, http.StatusBadGateway, mappedRes.StatusCode)
+		assert.Equal(t, http.StatusServiceUnavailable, fallbackRes.StatusCode)
+	}
+}
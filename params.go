@@ -0,0 +1,338 @@
+package openapirouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// parameterLocations defines the order in which parameters are added to the decoded values. If parameters in
+// different locations have the same name, the value of the later location is used.
+var parameterLocations = []string{
+	openapi3.ParameterInCookie,
+	openapi3.ParameterInHeader,
+	openapi3.ParameterInQuery,
+	openapi3.ParameterInPath,
+}
+
+// BindParams fills the struct pointed to by target with the parameters of a request handled by a Router. The values
+// are decoded according to the style and explode properties of the parameters in the OpenAPI specification, so
+// integers, numbers and booleans are typed correctly, arrays are split and objects, e.g. deepObject query parameters,
+// are assembled. Struct fields are matched by their json tag or, like encoding/json does, by their name ignoring the
+// case. If parameters in different locations share a name, path parameters take precedence over query parameters,
+// query parameters over header and header over cookie parameters. The parameters are only decoded when they are bound.
+// If a value cannot be decoded, an HTTPError with the status code http.StatusBadRequest is returned.
+func BindParams(request *http.Request, target interface{}) error {
+	route, ok := RouteFromContext(request.Context())
+	pathParams, found := request.Context().Value(pathParamsKey).(map[string]string)
+	if !ok || !found {
+		return errors.New("request was not handled by a Router")
+	}
+	values, err := decodeParameters(route, request, pathParams)
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// Bind fills the struct pointed to by target with the parameters of the request. See BindParams.
+func (params Params) Bind(target interface{}) error {
+	return BindParams(params.Request, target)
+}
+
+// decodeParameters decodes all parameters of the operation of a route, which are present in the request.
+func decodeParameters(route *routers.Route, request *http.Request, pathParams map[string]string) (
+	map[string]interface{}, error) {
+	parameters := route.Operation.Parameters
+	for _, parameterRef := range route.PathItem.Parameters {
+		if parameters.GetByInAndName(parameterRef.Value.In, parameterRef.Value.Name) == nil {
+			parameters = append(parameters, parameterRef)
+		}
+	}
+	values := make(map[string]interface{})
+	for _, location := range parameterLocations {
+		for _, parameterRef := range parameters {
+			parameter := parameterRef.Value
+			if parameter.In != location {
+				continue
+			}
+			value, found, err := decodeParameter(parameter, request, pathParams)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %q in %s could not be decoded: %w", parameter.Name, parameter.In, err)
+			}
+			if found {
+				values[parameter.Name] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+// decodeParameter decodes the value of a single parameter. It returns false, if the parameter is not present.
+func decodeParameter(parameter *openapi3.Parameter, request *http.Request, pathParams map[string]string) (
+	interface{}, bool, error) {
+	if parameter.Schema == nil {
+		return decodeContentParameter(parameter, request, pathParams)
+	}
+	method, err := parameter.SerializationMethod()
+	if err != nil {
+		return nil, false, err
+	}
+	schema := parameter.Schema.Value
+	if parameter.In == openapi3.ParameterInQuery {
+		return decodeQueryParameter(parameter.Name, method, schema, request)
+	}
+	raw, found := rawParameter(parameter, request, pathParams)
+	if !found {
+		return nil, false, nil
+	}
+	raw, separator, err := trimStylePrefix(parameter.Name, method, schema, raw)
+	if err != nil {
+		return nil, true, err
+	}
+	switch schema.Type {
+	case openapi3.TypeArray:
+		value, err := parseArray(strings.Split(raw, separator), schema)
+		return value, true, err
+	case openapi3.TypeObject:
+		properties, err := splitProperties(raw, separator, method.Explode)
+		if err != nil {
+			return nil, true, err
+		}
+		value, err := parseObject(properties, schema)
+		return value, true, err
+	default:
+		value, err := parsePrimitive(raw, schema)
+		return value, true, err
+	}
+}
+
+// decodeContentParameter decodes a parameter defined by a JSON media type instead of a schema.
+func decodeContentParameter(parameter *openapi3.Parameter, request *http.Request, pathParams map[string]string) (
+	interface{}, bool, error) {
+	var raw string
+	var found bool
+	if parameter.In == openapi3.ParameterInQuery {
+		raw, found = request.URL.Query().Get(parameter.Name), request.URL.Query().Has(parameter.Name)
+	} else {
+		raw, found = rawParameter(parameter, request, pathParams)
+	}
+	if !found || parameter.Content.Get("application/json") == nil {
+		return nil, false, nil
+	}
+	var value interface{}
+	err := json.Unmarshal([]byte(raw), &value)
+	return value, true, err
+}
+
+// rawParameter extracts the raw value of a path, header or cookie parameter.
+func rawParameter(parameter *openapi3.Parameter, request *http.Request, pathParams map[string]string) (
+	string, bool) {
+	switch parameter.In {
+	case openapi3.ParameterInPath:
+		raw, found := pathParams[parameter.Name]
+		return raw, found
+	case openapi3.ParameterInHeader:
+		values := request.Header.Values(parameter.Name)
+		return strings.Join(values, ","), len(values) > 0
+	case openapi3.ParameterInCookie:
+		cookie, err := request.Cookie(parameter.Name)
+		if err != nil {
+			return "", false
+		}
+		return cookie.Value, true
+	default:
+		return "", false
+	}
+}
+
+// trimStylePrefix removes the prefix of the label and matrix styles from a raw value and returns the separator of
+// the values of arrays and objects. Like the validation of the openapi3filter package, it fails if the prefix is
+// missing.
+func trimStylePrefix(name string, method *openapi3.SerializationMethod, schema *openapi3.Schema, raw string) (
+	string, string, error) {
+	switch method.Style {
+	case openapi3.SerializationLabel:
+		raw, found := strings.CutPrefix(raw, ".")
+		if !found {
+			return "", "", errors.New(`value must be prefixed with "."`)
+		}
+		if method.Explode {
+			return raw, ".", nil
+		}
+		return raw, ",", nil
+	case openapi3.SerializationMatrix:
+		if method.Explode && schema.Type == openapi3.TypeObject {
+			// ;x=1;y=2
+			raw, found := strings.CutPrefix(raw, ";")
+			if !found {
+				return "", "", errors.New(`value must be prefixed with ";"`)
+			}
+			return raw, ";", nil
+		}
+		if method.Explode {
+			// ;name=a;name=b
+			raw, found := strings.CutPrefix(raw, ";")
+			segments := strings.Split(raw, ";")
+			for i := 0; found && i < len(segments); i++ {
+				segments[i], found = strings.CutPrefix(segments[i], name+"=")
+			}
+			if !found {
+				return "", "", fmt.Errorf("value must be prefixed with %q", ";"+name+"=")
+			}
+			return strings.Join(segments, ";"), ";", nil
+		}
+		raw, found := strings.CutPrefix(raw, ";"+name+"=")
+		if !found {
+			return "", "", fmt.Errorf("value must be prefixed with %q", ";"+name+"=")
+		}
+		return raw, ",", nil
+	}
+	return raw, ",", nil
+}
+
+// decodeQueryParameter decodes a query parameter in the form, spaceDelimited, pipeDelimited or deepObject style.
+func decodeQueryParameter(name string, method *openapi3.SerializationMethod, schema *openapi3.Schema,
+	request *http.Request) (interface{}, bool, error) {
+	query := request.URL.Query()
+	switch schema.Type {
+	case openapi3.TypeArray:
+		values, found := query[name]
+		if !found {
+			return nil, false, nil
+		}
+		if !method.Explode && len(values) > 0 {
+			values = strings.Split(values[0], querySeparator(method.Style))
+		}
+		value, err := parseArray(values, schema)
+		return value, true, err
+	case openapi3.TypeObject:
+		properties := make(map[string]string)
+		if method.Style == openapi3.SerializationDeepObject {
+			for key, values := range query {
+				if strings.HasPrefix(key, name+"[") && strings.HasSuffix(key, "]") && len(values) > 0 {
+					properties[key[len(name)+1:len(key)-1]] = values[0]
+				}
+			}
+		} else if method.Explode {
+			for property := range schema.Properties {
+				if query.Has(property) {
+					properties[property] = query.Get(property)
+				}
+			}
+		} else if query.Has(name) {
+			var err error
+			if properties, err = splitProperties(query.Get(name), querySeparator(method.Style), false); err != nil {
+				return nil, true, err
+			}
+		}
+		if len(properties) == 0 {
+			return nil, false, nil
+		}
+		value, err := parseObject(properties, schema)
+		return value, true, err
+	default:
+		if !query.Has(name) {
+			return nil, false, nil
+		}
+		value, err := parsePrimitive(query.Get(name), schema)
+		return value, true, err
+	}
+}
+
+// querySeparator returns the separator of the values of a query parameter, which is not exploded.
+func querySeparator(style string) string {
+	switch style {
+	case openapi3.SerializationSpaceDelimited:
+		return " "
+	case openapi3.SerializationPipeDelimited:
+		return "|"
+	default:
+		return ","
+	}
+}
+
+// splitProperties splits the raw value of an object into its properties. Exploded objects are serialized as
+// "x=1,y=2", other objects as "x,1,y,2".
+func splitProperties(raw string, separator string, explode bool) (map[string]string, error) {
+	properties := make(map[string]string)
+	parts := strings.Split(raw, separator)
+	if explode {
+		for _, part := range parts {
+			pair := strings.Split(part, "=")
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("value must be a list of properties in the format \"name=value\" separated by %q",
+					separator)
+			}
+			properties[pair[0]] = pair[1]
+		}
+		return properties, nil
+	}
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("value must be a list of property names and values separated by %q", separator)
+	}
+	for i := 0; i+1 < len(parts); i += 2 {
+		properties[parts[i]] = parts[i+1]
+	}
+	return properties, nil
+}
+
+// parseArray parses all raw values by the item schema of the array.
+func parseArray(raw []string, schema *openapi3.Schema) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(raw))
+	for _, item := range raw {
+		var itemSchema *openapi3.Schema
+		if schema.Items != nil {
+			itemSchema = schema.Items.Value
+		}
+		value, err := parsePrimitive(item, itemSchema)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// parseObject parses all raw properties by the schema of the property.
+func parseObject(raw map[string]string, schema *openapi3.Schema) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(raw))
+	for key, item := range raw {
+		var propertySchema *openapi3.Schema
+		if property := schema.Properties[key]; property != nil {
+			propertySchema = property.Value
+		}
+		value, err := parsePrimitive(item, propertySchema)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// parsePrimitive parses a raw value to an integer, number, boolean or string according to the type of the schema.
+func parsePrimitive(raw string, schema *openapi3.Schema) (interface{}, error) {
+	if schema == nil {
+		return raw, nil
+	}
+	switch schema.Type {
+	case openapi3.TypeInteger:
+		return strconv.ParseInt(raw, 10, 64)
+	case openapi3.TypeNumber:
+		return strconv.ParseFloat(raw, 64)
+	case openapi3.TypeBoolean:
+		return strconv.ParseBool(raw)
+	default:
+		return raw, nil
+	}
+}
@@ -0,0 +1,385 @@
+package openapirouter
+
+import (
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const paramsTestSpec = `
+openapi: 3.0.3
+info:
+  title: Params-API
+  version: 1.0.0
+paths:
+  /clients/{id}/{ids}:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema:
+          type: integer
+    get:
+      operationId: getClient
+      parameters:
+        - in: path
+          name: ids
+          required: true
+          style: label
+          schema:
+            type: array
+            items:
+              type: integer
+        - in: query
+          name: active
+          schema:
+            type: boolean
+        - in: query
+          name: tags
+          schema:
+            type: array
+            items:
+              type: string
+        - in: query
+          name: codes
+          style: pipeDelimited
+          explode: false
+          schema:
+            type: array
+            items:
+              type: integer
+        - in: query
+          name: filter
+          style: deepObject
+          explode: true
+          schema:
+            type: object
+            properties:
+              name:
+                type: string
+              age:
+                type: integer
+        - in: header
+          name: X-Ratio
+          schema:
+            type: number
+        - in: cookie
+          name: session
+          schema:
+            type: string
+      responses:
+        '204':
+          description: no content
+`
+
+type clientFilter struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type clientParams struct {
+	ID      int64        `json:"id"`
+	IDs     []int        `json:"ids"`
+	Active  bool         `json:"active"`
+	Tags    []string     `json:"tags"`
+	Codes   []int        `json:"codes"`
+	Filter  clientFilter `json:"filter"`
+	Ratio   float64      `json:"X-Ratio"`
+	Session string
+}
+
+func getParamsRouterAndServer() (*Router, *httptest.Server) {
+	result, err := NewRouterFromData([]byte(paramsTestSpec))
+	if err != nil {
+		panic(err)
+	}
+	return result, httptest.NewServer(result)
+}
+
+func TestBindParams_ShouldBindTypedValues(t *testing.T) {
+	// given
+	router, server := getParamsRouterAndServer()
+	defer server.Close()
+	var params clientParams
+	var bindErr error
+	router.HandleOperation("getClient", func(request *http.Request, _ map[string]string) (*Response, error) {
+		bindErr = BindParams(request, &params)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	request, _ := http.NewRequest(http.MethodGet, server.URL+
+		"/clients/42/.1,2?active=true&tags=a&tags=b&codes=3|4&filter[name]=x&filter[age]=7", nil)
+	request.Header.Set("X-Ratio", "0.5")
+	request.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	// when
+	res, err := server.Client().Do(request)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	assert.Nil(t, bindErr)
+	assert.Equal(t, clientParams{
+		ID:      42,
+		IDs:     []int{1, 2},
+		Active:  true,
+		Tags:    []string{"a", "b"},
+		Codes:   []int{3, 4},
+		Filter:  clientFilter{Name: "x", Age: 7},
+		Ratio:   0.5,
+		Session: "abc",
+	}, params)
+}
+
+func TestBindParams_ShouldOmitMissingParameters(t *testing.T) {
+	// given
+	router, server := getParamsRouterAndServer()
+	defer server.Close()
+	params := clientParams{Active: true, Tags: []string{"default"}}
+	router.HandleOperation("getClient", func(request *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{StatusCode: http.StatusNoContent}, BindParams(request, &params)
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/clients/1/.2")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	assert.Equal(t, clientParams{ID: 1, IDs: []int{2}, Active: true, Tags: []string{"default"}}, params)
+}
+
+func TestBindParams_ShouldFailWithoutRouter(t *testing.T) {
+	// given
+	request := httptest.NewRequest(http.MethodGet, "/clients/1/.2", nil)
+	var params clientParams
+
+	// when
+	err := BindParams(request, &params)
+
+	// then
+	assert.NotNil(t, err)
+}
+
+func TestDecodeParameters_Styles(t *testing.T) {
+	testCases := []struct {
+		name     string
+		style    string
+		explode  bool
+		raw      string
+		schema   string
+		expected interface{}
+	}{
+		{"simple array", "simple", false, "1,2", "array", []interface{}{int64(1), int64(2)}},
+		{"simple object", "simple", false, "a,1", "object", map[string]interface{}{"a": int64(1)}},
+		{"simple exploded object", "simple", true, "a=1", "object", map[string]interface{}{"a": int64(1)}},
+		{"label exploded array", "label", true, ".1.2", "array", []interface{}{int64(1), int64(2)}},
+		{"matrix primitive", "matrix", false, ";p=1", "integer", int64(1)},
+		{"matrix array", "matrix", false, ";p=1,2", "array", []interface{}{int64(1), int64(2)}},
+		{"matrix exploded array", "matrix", true, ";p=1;p=2", "array", []interface{}{int64(1), int64(2)}},
+		{"matrix exploded object", "matrix", true, ";a=1", "object", map[string]interface{}{"a": int64(1)}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			router, err := NewRouterFromData([]byte(styleTestSpec("path", testCase.style, testCase.explode, testCase.schema)))
+			if err != nil {
+				panic(err)
+			}
+			request := httptest.NewRequest(http.MethodGet, "/styles/x", nil)
+			route, _, err := router.baseRouter.FindRoute(request)
+			if err != nil {
+				panic(err)
+			}
+
+			// when
+			values, err := decodeParameters(route, request, map[string]string{"p": testCase.raw})
+
+			// then
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.expected, values["p"])
+		})
+	}
+}
+
+func TestDecodeParameters_ShouldFailForInvalidValue(t *testing.T) {
+	// given
+	router, err := NewRouterFromData([]byte(styleTestSpec("path", "simple", false, "object")))
+	if err != nil {
+		panic(err)
+	}
+	request := httptest.NewRequest(http.MethodGet, "/styles/x", nil)
+	route, _, _ := router.baseRouter.FindRoute(request)
+
+	// when
+	_, err = decodeParameters(route, request, map[string]string{"p": "a,notAnInteger"})
+
+	// then
+	assert.NotNil(t, err)
+}
+
+func TestDecodeParameters_ShouldMatchValidation(t *testing.T) {
+	object := map[string]interface{}{"a": int64(1), "ap": "x"}
+	array := []interface{}{int64(1), int64(2)}
+	testCases := []struct {
+		in       string
+		style    string
+		explode  bool
+		schema   string
+		raw      string
+		expected interface{}
+	}{
+		{"path", "simple", false, "integer", "1", int64(1)},
+		{"path", "simple", false, "integer", "x", nil},
+		{"path", "simple", false, "array", "1,2", array},
+		{"path", "simple", false, "array", "1,x", nil},
+		{"path", "simple", false, "object", "a,1,ap,x", object},
+		{"path", "simple", false, "object", "a,1,ap", nil},
+		{"path", "simple", false, "object", "a,x,ap,x", nil},
+		{"path", "simple", true, "integer", "1", int64(1)},
+		{"path", "simple", true, "array", "1,2", array},
+		{"path", "simple", true, "object", "a=1,ap=x", object},
+		{"path", "simple", true, "object", "a=1,ap", nil},
+		{"path", "label", false, "integer", ".1", int64(1)},
+		{"path", "label", false, "integer", "1", nil},
+		{"path", "label", false, "array", ".1,2", array},
+		{"path", "label", false, "object", ".a,1,ap,x", object},
+		{"path", "label", true, "integer", ".1", int64(1)},
+		{"path", "label", true, "array", ".1.2", array},
+		{"path", "label", true, "array", "1.2", nil},
+		{"path", "label", true, "object", ".a=1.ap=x", object},
+		{"path", "matrix", false, "integer", ";p=1", int64(1)},
+		{"path", "matrix", false, "integer", ";q=1", nil},
+		{"path", "matrix", false, "array", ";p=1,2", array},
+		{"path", "matrix", false, "object", ";p=a,1,ap,x", object},
+		{"path", "matrix", true, "integer", ";p=1", int64(1)},
+		{"path", "matrix", true, "array", ";p=1;p=2", array},
+		{"path", "matrix", true, "array", ";p=1;ap=2", nil},
+		{"path", "matrix", true, "object", ";a=1;ap=x", object},
+		{"path", "matrix", true, "object", "a=1;ap=x", nil},
+		{"query", "form", false, "integer", "p=1", int64(1)},
+		{"query", "form", false, "integer", "p=x", nil},
+		{"query", "form", false, "array", "p=1,2", array},
+		{"query", "form", false, "object", "p=a,1,ap,x", object},
+		{"query", "form", false, "object", "p=a,1,ap", nil},
+		{"query", "form", true, "integer", "p=1", int64(1)},
+		{"query", "form", true, "array", "p=1&p=2", array},
+		{"query", "form", true, "array", "p=1&p=x", nil},
+		{"query", "form", true, "object", "a=1&ap=x", object},
+		{"query", "form", true, "object", "a=x&ap=x", nil},
+		{"query", "spaceDelimited", false, "array", "p=1%202", array},
+		{"query", "pipeDelimited", false, "array", "p=1%7C2", array},
+		{"query", "pipeDelimited", false, "array", "p=1%7Cx", nil},
+		{"query", "deepObject", true, "object", "p%5Ba%5D=1&p%5Bap%5D=x", object},
+		{"query", "deepObject", true, "object", "p%5Ba%5D=x&p%5Bap%5D=x", nil},
+		{"header", "simple", false, "integer", "1", int64(1)},
+		{"header", "simple", false, "integer", "x", nil},
+		{"header", "simple", false, "array", "1,2", array},
+		{"header", "simple", false, "object", "a,1,ap,x", object},
+		{"header", "simple", false, "object", "a,1,ap", nil},
+		{"header", "simple", true, "integer", "1", int64(1)},
+		{"header", "simple", true, "array", "1,2", array},
+		{"header", "simple", true, "object", "a=1,ap=x", object},
+		{"header", "simple", true, "object", "a=1,ap", nil},
+		{"cookie", "form", false, "integer", "1", int64(1)},
+		{"cookie", "form", false, "integer", "x", nil},
+		{"cookie", "form", false, "array", "1,2", array},
+		{"cookie", "form", false, "array", "1,x", nil},
+		{"cookie", "form", false, "object", "a,1,ap,x", object},
+		{"cookie", "form", false, "object", "a,1,ap", nil},
+		{"cookie", "form", true, "integer", "1", int64(1)},
+	}
+	for _, testCase := range testCases {
+		name := fmt.Sprintf("%s %s explode=%t %s %s", testCase.in, testCase.style, testCase.explode, testCase.schema,
+			testCase.raw)
+		t.Run(name, func(t *testing.T) {
+			// given
+			router, err := NewRouterFromData([]byte(styleTestSpec(testCase.in, testCase.style, testCase.explode,
+				testCase.schema)))
+			if err != nil {
+				panic(err)
+			}
+			request := styleTestRequest(testCase.in, testCase.raw)
+			route, pathParams, err := router.baseRouter.FindRoute(request)
+			if err != nil {
+				panic(err)
+			}
+
+			// when
+			validationErr := openapi3filter.ValidateRequest(request.Context(), &openapi3filter.RequestValidationInput{
+				Request: request, PathParams: pathParams, Route: route, Options: &openapi3filter.Options{}})
+			values, err := decodeParameters(route, request, pathParams)
+
+			// then
+			if testCase.expected == nil {
+				assert.NotNil(t, validationErr)
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, validationErr)
+				assert.Nil(t, err)
+				assert.Equal(t, testCase.expected, values["p"])
+			}
+		})
+	}
+}
+
+func styleTestRequest(in string, raw string) *http.Request {
+	switch in {
+	case "path":
+		return httptest.NewRequest(http.MethodGet, "/styles/"+raw, nil)
+	case "query":
+		return httptest.NewRequest(http.MethodGet, "/styles?"+raw, nil)
+	}
+	request := httptest.NewRequest(http.MethodGet, "/styles", nil)
+	if in == "header" {
+		request.Header.Set("p", raw)
+	} else {
+		request.Header.Set("Cookie", "p="+raw)
+	}
+	return request
+}
+
+func styleTestSpec(in string, style string, explode bool, schemaType string) string {
+	explodeValue := "false"
+	if explode {
+		explodeValue = "true"
+	}
+	path, required := "/styles", "false"
+	if in == "path" {
+		path, required = "/styles/{p}", "true"
+	}
+	schema := "type: " + schemaType
+	switch schemaType {
+	case "array":
+		schema += "\n            items:\n              type: integer"
+	case "object":
+		schema += "\n            properties:\n              a:\n                type: integer" +
+			"\n              ap:\n                type: string"
+	}
+	return `
+openapi: 3.0.3
+info:
+  title: Style-API
+  version: 1.0.0
+paths:
+  ` + path + `:
+    get:
+      parameters:
+        - in: ` + in + `
+          name: p
+          required: ` + required + `
+          style: ` + style + `
+          explode: ` + explodeValue + `
+          schema:
+            ` + schema + `
+      responses:
+        '204':
+          description: no content
+`
+}
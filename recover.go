@@ -0,0 +1,58 @@
+package openapirouter
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError is the error a HandleRequestFunction is recovered with, if it panics. Like any other error returned by a
+// handler function, it is mapped by the error mapper of the Router, so the response can be customized with MapError
+// or WithErrorFallback. Without a matching mapping, the request is answered with an Internal Server Error.
+type PanicError struct {
+	// Value passed to panic
+	Value interface{}
+	// Stack trace of the goroutine at the time of the panic
+	Stack []byte
+}
+
+// Error returns the message of the error, which contains the value passed to panic.
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.Value)
+}
+
+// PanicHandler is called with every PanicError recovered by the Router, e.g. to report the panic to a crash reporting
+// service. It is called before the response is written.
+type PanicHandler func(request *http.Request, err *PanicError)
+
+// WithPanicHandler sets the PanicHandler which is called for every panic recovered from a HandleRequestFunction.
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(router *Router) {
+		router.panicHandler = handler
+	}
+}
+
+// callHandlerFunction invokes the handlerFunction and recovers a panic as PanicError. The stack of the panic is logged
+// and passed to the PanicHandler. A panic with http.ErrAbortHandler is not recovered, because it is used to abort the
+// response deliberately.
+func (handler *requestHandler) callHandlerFunction(request *http.Request, pathParams map[string]string) (
+	response *Response, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+		panicErr := &PanicError{Value: recovered, Stack: debug.Stack()}
+		handler.logger.ErrorContext(request.Context(), "Request handler panicked",
+			"operationId", OperationIDFromContext(request.Context()), "panic", fmt.Sprint(recovered),
+			"stack", string(panicErr.Stack))
+		if handler.panicHandler != nil {
+			handler.panicHandler(request, panicErr)
+		}
+		response, err = nil, panicErr
+	}()
+	return handler.handlerFunction(request, pathParams)
+}
@@ -0,0 +1,90 @@
+package openapirouter
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecover_ShouldRespondWithInternalServerError(t *testing.T) {
+	// given
+	var output bytes.Buffer
+	var recovered *PanicError
+	var operationID string
+	router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+		WithLogger(slog.New(slog.NewTextHandler(&output, nil))),
+		WithPanicHandler(func(request *http.Request, err *PanicError) {
+			recovered = err
+			operationID = OperationIDFromContext(request.Context())
+		}))
+	defer server.Close()
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		panic("something went wrong")
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	}
+	if assert.NotNil(t, recovered) {
+		assert.Equal(t, "something went wrong", recovered.Value)
+		assert.Equal(t, "panic: something went wrong", recovered.Error())
+		assert.Contains(t, string(recovered.Stack), "recover_test.go")
+	}
+	assert.Equal(t, "getTestData", operationID)
+	assert.Contains(t, output.String(), "Request handler panicked")
+	assert.Contains(t, output.String(), "recover_test.go")
+	assert.NotContains(t, output.String(), "Request handler failed")
+}
+
+func TestRecover_ShouldMapPanicError(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	MapError(router, func(err *PanicError) *HTTPError {
+		return NewHTTPError(http.StatusServiceUnavailable, "temporarily unavailable")
+	})
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		var data *TestData
+		return &Response{StatusCode: http.StatusOK, Body: data.Data}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	}
+}
+
+func TestRecover_ShouldNotRecoverAbortHandler(t *testing.T) {
+	// given
+	called := false
+	router, err := NewRouter("testdata/test-api.yaml", WithPanicHandler(func(_ *http.Request, _ *PanicError) {
+		called = true
+	}))
+	if err != nil {
+		panic(err)
+	}
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		panic(http.ErrAbortHandler)
+	})
+
+	// when
+	serve := func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	}
+
+	// then
+	assert.PanicsWithValue(t, http.ErrAbortHandler, serve)
+	assert.False(t, called)
+}
@@ -1,8 +1,8 @@
 package openapirouter
 
 import (
-	"encoding/json"
-	"log"
+	"bytes"
+	"io"
 	"net/http"
 )
 
@@ -10,38 +10,127 @@ import (
 type Response struct {
 	// http StatusCode to return
 	StatusCode int
-	// Body of the http request to return. If it is set to string, a plain text response is return. If it is anything
-	// else, the response is returned in JSON format.
+	// Body of the http request to return. It is encoded with the Encoder of the media type negotiated from the Accept
+	// header of the request and the media types documented for the status code. Without documented media types, a
+	// string is returned as plain text and anything else in JSON format. An io.Reader or []byte Body is streamed to the
+	// client as is instead, see Attachment.
 	Body interface{}
-	// http Headers to add to the response
+	// http Headers to add to the response. If the Content-Type is specified, it is used instead of the default
+	// content type of the Body.
 	Headers map[string]string
 }
 
-// write is used by the requestHandler and writes the result of the request as an http response.
-func (response *Response) write(writer http.ResponseWriter) {
-	var err error
-	for key, value := range response.Headers {
-		writer.Header().Set(key, value)
-	}
-	switch data := response.Body.(type) {
+// write is used by the requestHandler and writes the result of the request as an http response without content
+// negotiation: a string Body is written as plain text, a streamed Body as application/octet-stream and any other Body
+// as JSON. If the body could not be written, an Internal Server Error is written instead and the error is returned.
+func (response *Response) write(writer http.ResponseWriter) error {
+	switch response.Body.(type) {
+	case nil:
+		return response.encode(writer, "", nil)
 	case string:
-		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		writer.WriteHeader(response.StatusCode)
-		_, err = writer.Write([]byte(data))
+		return response.encode(writer, "text/plain; charset=utf-8", EncodeText)
+	case io.Reader, []byte:
+		return response.stream(writer, "application/octet-stream")
 	default:
-		if data != nil {
-			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-			writer.WriteHeader(response.StatusCode)
-			err = json.NewEncoder(writer).Encode(response.Body)
-		} else {
-			writer.WriteHeader(response.StatusCode)
-			_, err = writer.Write(make([]byte, 0))
+		return response.encode(writer, "application/json; charset=utf-8", EncodeJSON)
+	}
+}
+
+// encode writes the Response with the Body encoded by the Encoder and the Content-Type, unless another Content-Type is
+// specified in the headers of the Response. The Body is encoded before anything is written, so an Internal Server
+// Error can be written instead, if the Body could not be encoded. In this case, the error is returned. A streamed Body
+// is written without the Encoder.
+func (response *Response) encode(writer http.ResponseWriter, contentType string, encoder Encoder) error {
+	if isStream(response.Body) {
+		return response.stream(writer, contentType)
+	}
+	var body bytes.Buffer
+	if response.Body != nil && encoder != nil {
+		if err := encoder(&body, response.Body); err != nil {
+			if response.StatusCode != http.StatusInternalServerError {
+				_ = error500Response.write(writer)
+			}
+			return err
 		}
+		setDefaultContentType(writer, contentType)
 	}
-	if err != nil {
-		log.Println("Could not write response", err)
-		if response.StatusCode != http.StatusInternalServerError {
-			error500Response.write(writer)
+	for key, value := range response.Headers {
+		writer.Header().Set(key, value)
+	}
+	writer.WriteHeader(response.StatusCode)
+	if body.Len() == 0 {
+		return nil
+	}
+	_, err := writer.Write(body.Bytes())
+	return err
+}
+
+// header returns the value of the header with the name in the headers of the Response. Like http.Header, the names
+// are compared in their canonical form, so "content-type" matches "Content-Type".
+func (response *Response) header(name string) (string, bool) {
+	name = http.CanonicalHeaderKey(name)
+	for key, value := range response.Headers {
+		if http.CanonicalHeaderKey(key) == name {
+			return value, true
 		}
 	}
+	return "", false
+}
+
+// setDefaultContentType sets the Content-Type header, unless it was already specified in the headers of the Response.
+func setDefaultContentType(writer http.ResponseWriter, contentType string) {
+	if writer.Header().Get("Content-Type") == "" {
+		writer.Header().Set("Content-Type", contentType)
+	}
+}
+
+// responseBuffer implements http.ResponseWriter and keeps the written response in memory, so it can be validated
+// before it is sent to the client. A streamed Body is not buffered, instead it is copied to the http.ResponseWriter
+// returned by stream after the status code and headers were validated.
+type responseBuffer struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+	streamed   bool
+	stream     func(buffer *responseBuffer) http.ResponseWriter
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+// Header is the implementation of http.ResponseWriter
+func (buffer *responseBuffer) Header() http.Header {
+	return buffer.header
+}
+
+// Write is the implementation of http.ResponseWriter
+func (buffer *responseBuffer) Write(data []byte) (int, error) {
+	if buffer.statusCode == 0 {
+		buffer.WriteHeader(http.StatusOK)
+	}
+	return buffer.body.Write(data)
+}
+
+// WriteHeader is the implementation of http.ResponseWriter
+func (buffer *responseBuffer) WriteHeader(statusCode int) {
+	if buffer.statusCode == 0 {
+		buffer.statusCode = statusCode
+	}
+}
+
+// writeTo sends the buffered response to the actual http.ResponseWriter.
+func (buffer *responseBuffer) writeTo(writer http.ResponseWriter) error {
+	for key, values := range buffer.header {
+		writer.Header()[key] = values
+	}
+	if buffer.statusCode == 0 {
+		buffer.statusCode = http.StatusOK
+	}
+	writer.WriteHeader(buffer.statusCode)
+	if buffer.body.Len() > 0 {
+		_, err := writer.Write(buffer.body.Bytes())
+		return err
+	}
+	return nil
 }
@@ -65,3 +65,20 @@ func TestWriteResponse_ShouldWriteEmptyResponse(t *testing.T) {
 	assert.Empty(t, recorder.Body.String())
 	assert.Empty(t, recorder.Header().Get("Content-Type"))
 }
+
+func TestWriteResponse_ShouldKeepSpecifiedContentType(t *testing.T) {
+	// given
+	recorder := httptest.NewRecorder()
+	response := &Response{
+		Body:       TestData{Data: "bla"},
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/vnd.test+json"},
+	}
+
+	//when
+	_ = response.write(recorder)
+
+	//then
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/vnd.test+json", recorder.Header().Get("Content-Type"))
+}
@@ -0,0 +1,73 @@
+package openapirouter
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// RouteInfo describes the operation of the OpenAPI specification matched by a request. It is available to middleware
+// and handler functions by RouteInfoFromContext, e.g. to label logs and metrics or to evaluate feature flags.
+type RouteInfo struct {
+	// the matched route with the path item and the operation of the specification
+	Route *routers.Route
+	// operationId of the operation, empty if the operation has none
+	OperationID string
+	// HTTP method of the operation
+	Method string
+	// path template of the specification without the server URL, e.g. "/clients/{client}"
+	PathTemplate string
+	// tags of the operation
+	Tags []string
+	// specification extensions of the operation, e.g. "x-feature-flag"
+	Extensions map[string]interface{}
+	// path parameters extracted from the URL
+	PathParams map[string]string
+}
+
+// newRouteInfo creates the RouteInfo of a matched route.
+func newRouteInfo(route *routers.Route, pathParams map[string]string) *RouteInfo {
+	return &RouteInfo{
+		Route:        route,
+		OperationID:  route.Operation.OperationID,
+		Method:       route.Method,
+		PathTemplate: route.Path,
+		Tags:         route.Operation.Tags,
+		Extensions:   route.Operation.Extensions,
+		PathParams:   pathParams,
+	}
+}
+
+// Extension returns the value of the specification extension of the operation with the name, e.g. "x-feature-flag".
+// The second return value is false, if the operation has no such extension.
+func (info *RouteInfo) Extension(name string) (interface{}, bool) {
+	value, ok := info.Extensions[name]
+	return value, ok
+}
+
+// RouteInfoFromContext returns the RouteInfo of the operation matched by the request with the context. The second
+// return value is false, if the request did not match any route.
+func RouteInfoFromContext(ctx context.Context) (*RouteInfo, bool) {
+	info, ok := ctx.Value(routeKey).(*RouteInfo)
+	return info, ok
+}
+
+// RouteFromContext returns the route of the OpenAPI specification matched by the request with the context. The route
+// contains the path item and the operation, e.g. with its operationId, tags and extensions. The second return value is
+// false, if the request did not match any route.
+func RouteFromContext(ctx context.Context) (*routers.Route, bool) {
+	info, ok := RouteInfoFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return info.Route, true
+}
+
+// OperationIDFromContext returns the operationId of the operation matched by the request with the context. It is empty,
+// if the request did not match any route or the operation has no operationId.
+func OperationIDFromContext(ctx context.Context) string {
+	info, ok := RouteInfoFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return info.OperationID
+}
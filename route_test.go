@@ -0,0 +1,87 @@
+package openapirouter
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const routeTestSpec = `
+openapi: 3.0.3
+info:
+  title: Route-API
+  version: 1.0.0
+servers:
+  - url: /api/v1
+paths:
+  /clients/{client}:
+    get:
+      operationId: getClient
+      tags: [clients]
+      x-feature-flag: client-details
+      parameters:
+        - in: path
+          name: client
+          required: true
+          schema:
+            type: string
+      responses:
+        '204':
+          description: no content
+`
+
+func TestRouteInfoFromContext_ShouldDescribeOperation(t *testing.T) {
+	// given
+	router, err := NewRouterFromData([]byte(routeTestSpec))
+	if err != nil {
+		panic(err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+	var info *RouteInfo
+	var operationID string
+	router.HandleOperation("getClient", func(request *http.Request, _ map[string]string) (*Response, error) {
+		info, _ = RouteInfoFromContext(request.Context())
+		operationID = OperationIDFromContext(request.Context())
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/api/v1/clients/42")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	assert.Equal(t, "getClient", operationID)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, "getClient", info.OperationID)
+		assert.Equal(t, http.MethodGet, info.Method)
+		assert.Equal(t, "/clients/{client}", info.PathTemplate)
+		assert.Equal(t, []string{"clients"}, info.Tags)
+		assert.Equal(t, map[string]string{"client": "42"}, info.PathParams)
+		flag, ok := info.Extension("x-feature-flag")
+		assert.True(t, ok)
+		assert.Equal(t, "client-details", flag)
+		assert.Same(t, router.operations["getClient"], info.Route.Operation)
+	}
+}
+
+func TestRouteInfoFromContext_WithoutRoute(t *testing.T) {
+	// given
+	ctx := context.Background()
+
+	// when
+	info, ok := RouteInfoFromContext(ctx)
+	route, routeOk := RouteFromContext(ctx)
+
+	// then
+	assert.False(t, ok)
+	assert.Nil(t, info)
+	assert.False(t, routeOk)
+	assert.Nil(t, route)
+	assert.Empty(t, OperationIDFromContext(ctx))
+}
@@ -2,87 +2,392 @@ package openapirouter
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/getkin/kin-openapi/routers"
 	"github.com/getkin/kin-openapi/routers/gorillamux"
-	"log"
+	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
-	"reflect"
+	"net/url"
+	"path"
+	"strings"
 )
 
 // The Router which implements the described features. It implements http.Handler to be compatible with existing HTTP
 // libraries.
 type Router struct {
-	baseRouter      routers.Router
-	errMapper       *errorMapper
-	implementations map[routers.Route]requestHandler
+	baseRouter              routers.Router
+	swagger                 *openapi3.T
+	errMapper               *errorMapper
+	operations              map[string]*openapi3.Operation
+	implementations         map[*openapi3.Operation]requestHandler
+	duplicates              []*openapi3.Operation
+	responseValidation      ResponseValidationMode
+	testMode                bool
+	options                 openapi3filter.Options
+	logger                  *slog.Logger
+	accessLog               *slog.Level
+	panicHandler            PanicHandler
+	encoders                *encoderRegistry
+	decoders                *decoderRegistry
+	uploadLimits            UploadLimits
+	notFoundHandler         http.Handler
+	methodNotAllowedHandler http.Handler
+	specValidation          []openapi3.ValidationOption
+	validateSpec            bool
+	serverURLs              []string
+	overrideServers         bool
+	authenticators          map[string]Authenticator
+	middlewares             []Middleware
+	operationMiddlewares    map[*openapi3.Operation][]Middleware
+	instrumentation         []Middleware
+	phaseTracer             PhaseTracer
 }
 
-// NewRouter creates a new Router with the path of a OpenAPI specification file in YAML or JSON format.
-func NewRouter(swaggerPath string) (*Router, error) {
+// NewRouter creates a new Router with the path of a OpenAPI specification file in YAML or JSON format. The behavior of
+// the Router can be configured with any number of Option.
+func NewRouter(swaggerPath string, opts ...Option) (*Router, error) {
 	swagger, err := openapi3.NewLoader().LoadFromFile(swaggerPath)
 	if err != nil {
 		return nil, err
 	}
-	router, err := gorillamux.NewRouter(swagger)
+	return NewRouterFromDoc(swagger, opts...)
+}
+
+// NewRouterFromData creates a new Router with the content of a OpenAPI specification in YAML or JSON format.
+func NewRouterFromData(data []byte, opts ...Option) (*Router, error) {
+	swagger, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewRouterFromDoc(swagger, opts...)
+}
+
+// NewRouterFromReader creates a new Router with a OpenAPI specification in YAML or JSON format which is read from the
+// io.Reader.
+func NewRouterFromReader(reader io.Reader, opts ...Option) (*Router, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewRouterFromData(data, opts...)
+}
+
+// NewRouterFromFS creates a new Router with the OpenAPI specification file of the given name inside a fs.FS, e.g. an
+// embed.FS. Relative references to other files ($ref) are resolved inside the same fs.FS.
+func NewRouterFromFS(fsys fs.FS, name string, opts ...Option) (*Router, error) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(_ *openapi3.Loader, location *url.URL) ([]byte, error) {
+		return fs.ReadFile(fsys, path.Clean(strings.TrimPrefix(location.Path, "/")))
+	}
+	swagger, err := loader.LoadFromFile(name)
 	if err != nil {
 		return nil, err
 	}
-	return &Router{
-		baseRouter:      router,
-		errMapper:       &errorMapper{errorMapping: make(map[reflect.Type]*HTTPError)},
-		implementations: make(map[routers.Route]requestHandler),
-	}, nil
+	return NewRouterFromDoc(swagger, opts...)
+}
+
+// NewRouterFromDoc creates a new Router with an already loaded OpenAPI specification, e.g. to build the specification
+// programmatically. All references of the specification need to be resolved.
+func NewRouterFromDoc(swagger *openapi3.T, opts ...Option) (*Router, error) {
+	router := &Router{
+		errMapper:            newErrorMapper(),
+		operations:           make(map[string]*openapi3.Operation),
+		implementations:      make(map[*openapi3.Operation]requestHandler),
+		options:              openapi3filter.Options{MultiError: true},
+		logger:               slog.Default(),
+		authenticators:       make(map[string]Authenticator),
+		operationMiddlewares: make(map[*openapi3.Operation][]Middleware),
+		encoders:             newEncoderRegistry(),
+		decoders:             newDecoderRegistry(),
+	}
+	for _, opt := range opts {
+		opt(router)
+	}
+	if router.validateSpec {
+		if err := swagger.Validate(context.Background(), router.specValidation...); err != nil {
+			return nil, err
+		}
+	}
+	routingSpec := swagger
+	if router.overrideServers {
+		routingSpec = router.withServers(swagger)
+	}
+	baseRouter, err := gorillamux.NewRouter(routingSpec)
+	if err != nil {
+		return nil, err
+	}
+	router.baseRouter = baseRouter
+	router.swagger = swagger
+	for name := range router.authenticators {
+		if err := router.checkSecurityScheme(name); err != nil {
+			return nil, err
+		}
+	}
+	for _, pathItem := range swagger.Paths {
+		for _, operation := range pathItem.Operations() {
+			if operation.OperationID != "" {
+				router.operations[operation.OperationID] = operation
+			}
+		}
+	}
+	return router, nil
+}
+
+// withServers returns a shallow copy of the specification with the servers configured by WithServerURLs, so the
+// specification of the caller is not modified.
+func (router *Router) withServers(swagger *openapi3.T) *openapi3.T {
+	routingSpec := *swagger
+	routingSpec.Servers = make(openapi3.Servers, 0, len(router.serverURLs))
+	for _, serverURL := range router.serverURLs {
+		routingSpec.Servers = append(routingSpec.Servers, &openapi3.Server{URL: serverURL})
+	}
+	return &routingSpec
 }
 
 // Implementation of http.Handler that finds the requestHandler for an incoming request and validates the requests. It
-// also adds the pathParameters to the requests Context so they can be extracted by the requestHandler
+// also adds the pathParameters to the requests Context so they can be extracted by the requestHandler. The middleware
+// added with Use wraps the handling of every request after the route was matched and is wrapped by the access log and
+// the instrumentation added with WithInstrumentation.
 func (router *Router) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	var response *Response
+	var handler http.Handler
+	request, _ = observe(request)
 	route, pathParams, err := router.baseRouter.FindRoute(request)
 	if err != nil {
-		if err.Error() == routers.ErrMethodNotAllowed.Error() {
-			response = NewHTTPError(http.StatusMethodNotAllowed, err.Error()).ToResponse()
-		} else {
-			response = NewHTTPError(http.StatusNotFound, err.Error()).ToResponse()
+		handler = router.unmatchedHandler(err)
+	} else {
+		request = request.WithContext(context.WithValue(request.Context(), routeKey, newRouteInfo(route, pathParams)))
+		handler = chain(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			router.serveOperation(writer, request, route, pathParams)
+		}), router.operationMiddlewares[route.Operation])
+	}
+	handler = chain(handler, router.middlewares)
+	if router.accessLog != nil {
+		handler = router.logAccess(handler, route)
+	}
+	handler = chain(handler, router.instrumentation)
+	handler.ServeHTTP(writer, request)
+}
+
+// unmatchedHandler returns the http.Handler for requests which do not match any route of the OpenAPI specification.
+func (router *Router) unmatchedHandler(err error) http.Handler {
+	handler, reason := router.notFoundHandler, RejectionNotFound
+	if handler == nil {
+		handler = router.errorHandler(NewHTTPError(http.StatusNotFound, err.Error()))
+	}
+	if err.Error() == routers.ErrMethodNotAllowed.Error() {
+		handler, reason = router.methodNotAllowedHandler, RejectionMethodNotAllowed
+		if handler == nil {
+			handler = router.errorHandler(NewHTTPError(http.StatusMethodNotAllowed, err.Error()))
 		}
-		response.write(writer)
+	}
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		reject(request.Context(), reason, err)
+		handler.ServeHTTP(writer, request)
+	})
+}
+
+// errorHandler returns an http.Handler which responds with the HTTPError.
+func (router *Router) errorHandler(httpErr *HTTPError) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		router.writeResponse(writer, request, router.errMapper.toResponse(httpErr))
+	})
+}
+
+// serveOperation validates a request for the operation of a matched route and invokes the requestHandler added for
+// the operation.
+func (router *Router) serveOperation(writer http.ResponseWriter, request *http.Request, route *routers.Route,
+	pathParams map[string]string) {
+	handler, ok := router.implementations[route.Operation]
+	if !ok {
+		reject(request.Context(), RejectionNotImplemented, nil)
+		router.writeResponse(writer, request, router.errMapper.toResponse(NewHTTPError(http.StatusNotImplemented)))
 		return
 	}
-	handler, ok := router.implementations[*route]
-	if ok {
-		validationInput := &openapi3filter.RequestValidationInput{
-			Request:     request,
-			PathParams:  pathParams,
-			QueryParams: request.URL.Query(),
-			Route:       route,
-			Options:     handler.options,
-		}
-		err = openapi3filter.ValidateRequest(request.Context(), validationInput)
+	authentication := newAuthentication()
+	request = request.WithContext(context.WithValue(request.Context(), authenticationKey, authentication))
+	request = request.WithContext(context.WithValue(request.Context(), decodersKey, router.decoders))
+	validationInput := &openapi3filter.RequestValidationInput{
+		Request:     request,
+		PathParams:  pathParams,
+		QueryParams: request.URL.Query(),
+		Route:       route,
+		Options:     handler.options,
+	}
+	content := uploadContent(request, route.Operation)
+	var decodedContent *openapi3.MediaType
+	if content == nil {
+		decodedContent = router.decoders.decodes(request, route.Operation)
+	}
+	if content != nil || decodedContent != nil {
+		options := *handler.options
+		options.ExcludeRequestBody = true
+		validationInput.Options = &options
+	}
+	validationCtx, endValidation := router.startPhase(request.Context(), "validate request")
+	err := openapi3filter.ValidateRequest(validationCtx, validationInput)
+	if decodedContent != nil && (err == nil || handler.options.MultiError) {
+		err = withBodyError(err, router.decoders.validateRequestBody(validationInput, decodedContent))
+	}
+	if err != nil {
+		endValidation(err)
+		response := router.validationErrorResponse(err)
+		reject(request.Context(), rejectionReason(response.StatusCode), err)
+		router.writeResponse(writer, request, response)
+		return
+	}
+	request = validationInput.Request
+	authentication.satisfy(router.securityRequirements(route.Operation))
+	if ok, mediaTypes := router.encoders.acceptable(request, route.Operation); !ok {
+		err := notAcceptableError(mediaTypes)
+		endValidation(nil)
+		reject(request.Context(), RejectionNotAcceptable, err)
+		router.writeResponse(writer, request, router.errMapper.toResponse(err))
+		return
+	}
+	if content != nil {
+		upload, err := router.readUpload(writer, validationInput, content)
 		if err != nil {
-			switch typedErr := err.(type) {
-			case *openapi3filter.RequestError:
-				response = NewHTTPError(http.StatusBadRequest, err.Error()).ToResponse()
-			case *openapi3filter.SecurityRequirementsError:
-				status := http.StatusUnauthorized
-				if len(typedErr.Errors) > 0 && typedErr.Errors[0] == openapi3filter.ErrAuthenticationServiceMissing {
-					status = http.StatusInternalServerError
-				}
-				response = NewHTTPError(status, "request could not be authorized").ToResponse()
-			default:
-				response = NewHTTPError(http.StatusInternalServerError, "error validating request").ToResponse()
-			}
-			response.write(writer)
+			endValidation(err)
+			response := router.uploadErrorResponse(err)
+			reject(request.Context(), rejectionReason(response.StatusCode), err)
+			router.writeResponse(writer, request, response)
 			return
 		}
-		request = validationInput.Request
-		ctx := context.WithValue(request.Context(), pathParamsKey, pathParams)
+		defer func() {
+			if err := upload.RemoveAll(); err != nil {
+				router.logger.ErrorContext(request.Context(), "Could not remove uploaded files", "error", err)
+			}
+		}()
+		request = withUpload(request, upload)
+	}
+	endValidation(nil)
+	ctx := context.WithValue(request.Context(), pathParamsKey, pathParams)
+	ctx, endHandling := router.startPhase(ctx, "handle request")
+	_, observation := observe(request)
+	defer func() {
+		endHandling(observation.handlerErr)
+	}()
+	if router.responseValidation.enabled(router.testMode) {
+		router.serveValidated(writer, request.WithContext(ctx), &handler, validationInput)
+	} else {
 		handler.ServeHTTP(writer, request.WithContext(ctx))
+	}
+}
+
+// validationErrorResponse creates the response for a request which failed the validation. Requests failing the
+// security requirements are answered with a WWW-Authenticate header.
+func (router *Router) validationErrorResponse(err error) *Response {
+	httpErr := validationHTTPError(err)
+	response := router.errMapper.toResponse(httpErr)
+	var securityErr *openapi3filter.SecurityRequirementsError
+	if httpErr.StatusCode != http.StatusInternalServerError && errors.As(err, &securityErr) {
+		if challenge := router.challenge(securityErr); challenge != "" {
+			if response.Headers == nil {
+				response.Headers = make(map[string]string)
+			}
+			response.Headers["WWW-Authenticate"] = challenge
+		}
+	}
+	return response
+}
+
+// validationHTTPError converts the error returned by openapi3filter.ValidateRequest to an HTTPError. Failed security
+// requirements take precedence over other violations. Invalid requests are described in detail by the extension
+// member "errors" containing a ValidationError for every violation.
+func validationHTTPError(err error) *HTTPError {
+	var securityErr *openapi3filter.SecurityRequirementsError
+	if errors.As(err, &securityErr) {
+		if len(securityErr.Errors) > 0 && securityErr.Errors[0] == openapi3filter.ErrAuthenticationServiceMissing {
+			return NewHTTPError(http.StatusInternalServerError, "request could not be authorized")
+		}
+		if isForbidden(securityErr) {
+			return NewHTTPError(http.StatusForbidden, "request is not permitted")
+		}
+		return NewHTTPError(http.StatusUnauthorized, "request could not be authorized")
+	}
+	validationErrors := requestValidationErrors(err)
+	if len(validationErrors) == 0 {
+		return NewHTTPError(http.StatusInternalServerError, "error validating request")
+	}
+	var details []string
+	if multiErr, ok := err.(openapi3.MultiError); ok {
+		for _, e := range multiErr {
+			details = append(details, e.Error())
+		}
 	} else {
-		response = NewHTTPError(http.StatusNotImplemented).ToResponse()
-		response.write(writer)
+		details = append(details, err.Error())
 	}
+	return NewHTTPError(http.StatusBadRequest, details...).WithExtension("errors", validationErrors)
+}
+
+// fail logs the error caused by an invalid configuration of the Router and panics with it.
+func (router *Router) fail(err error) {
+	router.logger.Error("Invalid router configuration", "error", err)
+	panic(err)
+}
+
+// writeResponse writes the response to the request and logs any error which occurs during writing.
+func (router *Router) writeResponse(writer http.ResponseWriter, request *http.Request, response *Response) {
+	if err := response.write(writer); err != nil {
+		router.logger.ErrorContext(request.Context(), "Could not write response", "error", err)
+	}
+}
+
+// serveValidated invokes the handler with a responseBuffer and validates the buffered response against the
+// specification before it is written. Depending on the ResponseValidationMode, violations are only logged or the
+// response is replaced with an Internal Server Error. Streamed bodies are not buffered, only the status code and the
+// headers of their responses are validated before the body is copied to the client.
+func (router *Router) serveValidated(writer http.ResponseWriter, request *http.Request, handler http.Handler,
+	validationInput *openapi3filter.RequestValidationInput) {
+	buffer := newResponseBuffer()
+	buffer.stream = func(buffer *responseBuffer) http.ResponseWriter {
+		if !router.checkResponse(writer, request, validationInput, buffer) {
+			return nil
+		}
+		if err := buffer.writeTo(writer); err != nil {
+			router.logger.ErrorContext(request.Context(), "Could not write response", "error", err)
+		}
+		return writer
+	}
+	handler.ServeHTTP(buffer, request)
+	if buffer.streamed || !router.checkResponse(writer, request, validationInput, buffer) {
+		return
+	}
+	if err := buffer.writeTo(writer); err != nil {
+		router.logger.ErrorContext(request.Context(), "Could not write response", "error", err)
+	}
+}
+
+// checkResponse validates the buffered response and logs its violations of the specification. If the
+// ResponseValidationMode fails on errors, an invalid response is replaced with an Internal Server Error and false is
+// returned.
+func (router *Router) checkResponse(writer http.ResponseWriter, request *http.Request,
+	validationInput *openapi3filter.RequestValidationInput, buffer *responseBuffer) bool {
+	err := validateResponse(request.Context(), router.decoders, validationInput, buffer)
+	if err == nil {
+		return true
+	}
+	router.logger.ErrorContext(request.Context(), "Response does not match specification",
+		"operationId", OperationIDFromContext(request.Context()), "error", err)
+	if !router.responseValidation.failsOnError() {
+		return true
+	}
+	router.writeResponse(writer, request, router.errMapper.toResponse(NewHTTPError(http.StatusInternalServerError)))
+	return false
+}
+
+// SetResponseValidationMode enables or disables the validation of the responses returned by the
+// HandleRequestFunction implementations against the OpenAPI specification. Responses with undocumented status codes,
+// content types or bodies which do not match the schema are treated as invalid. See ResponseValidationMode for the
+// available modes.
+func (router *Router) SetResponseValidationMode(mode ResponseValidationMode) {
+	router.responseValidation = mode
 }
 
 // AddRequestHandler creates a new requestHandler for a specified method and path. It is used to set an implementation
@@ -95,35 +400,89 @@ func (router *Router) AddRequestHandler(method string, path string, handleFunc H
 // implementation for an endpoint. The function panics, if the endpoint is not specified in the OpenAPI specification.
 // In Addition to AddRequestHandler adds an openapi3filter.AuthenticationFunc which is necessary to validate a request
 // with specified SecurityRequirements. If SecurityRequirements are specified for a resource without
-// openapi3filter.AuthenticationFunc, the router will respond with http.StatusInternalServerError.
+// openapi3filter.AuthenticationFunc or an Authenticator registered with RegisterSecurityScheme, the router will respond
+// with http.StatusInternalServerError.
 func (router *Router) AddRequestHandlerWithAuthFunc(method string, path string, handleFunc HandleRequestFunction,
 	authFunc openapi3filter.AuthenticationFunc) {
 	request, err := http.NewRequest(method, path, nil)
 	if err != nil {
-		log.Panicln(err)
+		router.fail(err)
 	}
 	route, _, err := router.baseRouter.FindRoute(request)
 	if err != nil {
-		log.Panicln(err)
+		router.fail(err)
 	}
+	router.addImplementation(route.Operation, handleFunc, authFunc)
+}
 
-	options := &openapi3filter.Options{}
+// HandleOperation creates a new requestHandler for the operation with the specified operationId. In contrast to
+// AddRequestHandler, the path does not need to be repeated, so it works for every route regardless of path parameters
+// or the servers of the OpenAPI specification. The function panics, if no operation with the operationId is specified
+// in the OpenAPI specification.
+func (router *Router) HandleOperation(operationID string, handleFunc HandleRequestFunction) {
+	router.HandleOperationWithAuthFunc(operationID, handleFunc, nil)
+}
 
-	if authFunc != nil {
-		options.AuthenticationFunc = authFunc
+// HandleOperationWithAuthFunc creates a new requestHandler for the operation with the specified operationId. In
+// Addition to HandleOperation adds an openapi3filter.AuthenticationFunc which is necessary to validate a request with
+// specified SecurityRequirements. The function panics, if no operation with the operationId is specified in the
+// OpenAPI specification.
+func (router *Router) HandleOperationWithAuthFunc(operationID string, handleFunc HandleRequestFunction,
+	authFunc openapi3filter.AuthenticationFunc) {
+	operation, ok := router.operations[operationID]
+	if !ok {
+		router.fail(fmt.Errorf("no operation with operationId %s is specified", operationID))
+	}
+	router.addImplementation(operation, handleFunc, authFunc)
+}
+
+// addImplementation sets the requestHandler for an operation of the OpenAPI specification.
+func (router *Router) addImplementation(operation *openapi3.Operation, handleFunc HandleRequestFunction,
+	authFunc openapi3filter.AuthenticationFunc) {
+	options := router.options
+
+	if _, ok := router.implementations[operation]; ok {
+		router.duplicates = append(router.duplicates, operation)
 	}
+	dispatchesAuth := authFunc == nil
+	authenticator := router.authenticate
+	if !dispatchesAuth {
+		authenticator = func(ctx context.Context, input *openapi3filter.AuthenticationInput) (interface{}, error) {
+			return nil, authFunc(ctx, input)
+		}
+	}
+	options.AuthenticationFunc = recordPrincipal(authenticator)
 
-	router.implementations[*route] = requestHandler{
+	router.implementations[operation] = requestHandler{
 		errMapper:       router.errMapper,
 		handlerFunction: handleFunc,
-		options:         options,
+		options:         &options,
+		logger:          router.logger,
+		panicHandler:    router.panicHandler,
+		encoders:        router.encoders,
+		dispatchesAuth:  dispatchesAuth,
 	}
 }
 
 // AddErrorMapping adds a custom error that should be mapped to an error response. It uses the HTTPError to create the
 // response.
 // It takes an error and the response code this error should be mapped to. Additionally, any number of details can
-// be specified.
+// be specified. All errors of the same type are mapped, even if they are wrapped by another error. If multiple
+// mappings match an error, the mapping added first is used.
 func (router *Router) AddErrorMapping(err error, responseCode int, details ...string) {
-	router.errMapper.errorMapping[reflect.TypeOf(err)] = NewHTTPError(responseCode, details...)
+	router.errMapper.addTypeMapping(err, NewHTTPError(responseCode, details...))
+}
+
+// AddErrorMapper adds a function to map errors to a response. The function returns false for all errors it does not
+// map, so the next mapping is tried. Mappers are tried in the same order as the mappings of AddErrorMapping and
+// AddSentinelErrorMapping, so the mapping added first is used. See MapError for a type-safe alternative.
+func (router *Router) AddErrorMapper(mapper func(error) (*Response, bool)) {
+	router.errMapper.addMapper(mapper)
+}
+
+// AddSentinelErrorMapping adds a sentinel error value that should be mapped to an error response, e.g. sql.ErrNoRows.
+// In contrast to AddErrorMapping, only errors matching the sentinel error in terms of errors.Is are mapped, even if
+// they are wrapped by another error. If multiple mappings match an error, the mapping added first is used.
+func (router *Router) AddSentinelErrorMapping(sentinel error, responseCode int, details ...string) {
+	router.errMapper.addSentinelMapping(sentinel, NewHTTPError(responseCode, details...))
 }
@@ -3,15 +3,22 @@ package openapirouter
 import (
 	"bytes"
 	"context"
+	"embed"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 )
 
+//go:embed testdata
+var testdataFS embed.FS
+
 func getRouterAndServer() (*Router, *httptest.Server) {
 	result, err := NewRouter("testdata/test-api.yaml")
 	if err != nil {
@@ -390,3 +397,244 @@ func TestAddRoute_RouteNotDocumented(t *testing.T) {
 		})
 	})
 }
+
+func TestRouter_HandleOperation(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	called := false
+	router.HandleOperation("getPathParams", func(_ *http.Request, pathParams map[string]string) (*Response, error) {
+		called = true
+		assert.Equal(t, "value2", pathParams["param"])
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       TestData{Data: "test"},
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test/pathParams/value2")
+
+	// then
+	assert.Nil(t, err)
+	assert.True(t, called)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestRouter_HandleOperation_ServerPrefixes(t *testing.T) {
+	// given
+	router, err := NewRouter("testdata/test-api-servers.yaml")
+	if err != nil {
+		panic(err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+	var clients []string
+	router.HandleOperation("getClientData", func(_ *http.Request, pathParams map[string]string) (*Response, error) {
+		clients = append(clients, pathParams["client"])
+		return &Response{
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+
+	// when
+	res1, err1 := server.Client().Get(server.URL + "/api/v1/clients/first")
+	res2, err2 := server.Client().Get(server.URL + "/api/v2/clients/second")
+
+	// then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	if assert.NotNil(t, res1) && assert.NotNil(t, res2) {
+		assert.Equal(t, http.StatusOK, res1.StatusCode)
+		assert.Equal(t, http.StatusOK, res2.StatusCode)
+	}
+	assert.Equal(t, []string{"first", "second"}, clients)
+}
+
+func TestRouter_HandleOperationWithAuthFunc(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.HandleOperationWithAuthFunc("getSecured", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+		}, nil
+	}, openapi3filter.NoopAuthenticationFunc)
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test/secured")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestHandleOperation_UnknownOperationID(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+
+	// when + then
+	assert.Panics(t, func() {
+		router.HandleOperation("unknownOperation", func(_ *http.Request, _ map[string]string) (*Response, error) {
+			return error500Response, nil
+		})
+	})
+}
+
+func assertRouterServesTestData(t *testing.T, router *Router) {
+	server := httptest.NewServer(router)
+	defer server.Close()
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       TestData{Data: "test"},
+		}, nil
+	})
+	router.SetResponseValidationMode(ResponseValidationFail)
+
+	res, err := server.Client().Get(server.URL + "/test")
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestNewRouterFromData(t *testing.T) {
+	// given
+	data, _ := os.ReadFile("testdata/test-api.yaml")
+
+	// when
+	router, err := NewRouterFromData(data)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, router) {
+		assertRouterServesTestData(t, router)
+	}
+}
+
+func TestNewRouterFromData_InvalidData(t *testing.T) {
+	// when
+	router, err := NewRouterFromData([]byte("invalid: [specification"))
+
+	// then
+	assert.NotNil(t, err)
+	assert.Nil(t, router)
+}
+
+func TestNewRouterFromReader(t *testing.T) {
+	// given
+	file, _ := os.Open("testdata/test-api.yaml")
+	defer file.Close()
+
+	// when
+	router, err := NewRouterFromReader(file)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, router) {
+		assertRouterServesTestData(t, router)
+	}
+}
+
+func TestNewRouterFromFS_ResolvesRelativeReferences(t *testing.T) {
+	// when
+	router, err := NewRouterFromFS(testdataFS, "testdata/split/test-api.yaml")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, router) {
+		assertRouterServesTestData(t, router)
+	}
+}
+
+func TestNewRouterFromFS_FileNotFound(t *testing.T) {
+	// when
+	router, err := NewRouterFromFS(testdataFS, "testdata/missing.yaml")
+
+	// then
+	assert.NotNil(t, err)
+	assert.Nil(t, router)
+}
+
+func TestNewRouterFromDoc(t *testing.T) {
+	// given
+	schema := openapi3.NewObjectSchema().WithProperty("data", openapi3.NewStringSchema())
+	schema.Required = []string{"data"}
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info:    &openapi3.Info{Title: "Test-API", Version: "1.0.0"},
+		Paths: openapi3.Paths{
+			"/test": &openapi3.PathItem{
+				Get: &openapi3.Operation{
+					OperationID: "getTestData",
+					Responses: openapi3.Responses{
+						"200": &openapi3.ResponseRef{
+							Value: openapi3.NewResponse().
+								WithDescription("Successful").
+								WithJSONSchema(schema),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// when
+	router, err := NewRouterFromDoc(doc)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, router) {
+		assertRouterServesTestData(t, router)
+	}
+}
+
+func TestRouter_AddSentinelErrorMapping(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.AddSentinelErrorMapping(errSentinel, http.StatusConflict, "already exists")
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, fmt.Errorf("creating client: %w", errSentinel)
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+	}
+}
+
+func TestRouter_MapError(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	MapError(router, func(err *NotFoundError) *HTTPError {
+		return NewHTTPError(http.StatusNotFound).WithExtension("id", err.ID)
+	})
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return nil, &NotFoundError{ID: "42"}
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		var body map[string]interface{}
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, "42", body["id"])
+	}
+}
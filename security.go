@@ -0,0 +1,313 @@
+package openapirouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Authenticator implements a security scheme of the OpenAPI specification. It is invoked for every request of an
+// operation which references the scheme in its security requirements and returns an error, if the request does not
+// contain valid credentials for the scheme. The AuthenticationInput contains the request, the name and definition of
+// the scheme and the scopes required by the operation. The principal returned for valid credentials, e.g. the user or
+// tenant, is passed to the handler function and can be read with Principal.
+type Authenticator func(ctx context.Context, input *openapi3filter.AuthenticationInput) (interface{}, error)
+
+// AuthenticationError is returned by an Authenticator to control the response for a request, which could not be
+// authenticated. By default, such requests are answered with http.StatusUnauthorized. If all security requirements of
+// the operation fail with a forbidden AuthenticationError, the credentials are valid, but lack the permission for the
+// operation, and the request is answered with http.StatusForbidden. Both responses contain a WWW-Authenticate header
+// with a challenge for every security scheme of the type http or oauth2 and openIdConnect of the operation.
+type AuthenticationError struct {
+	// whether the request was authenticated, but is not permitted
+	Forbidden bool
+	// error code added to Bearer challenges according to RFC 6750, e.g. "invalid_token" or "insufficient_scope"
+	Code string
+	// the underlying error
+	Err error
+}
+
+// NewUnauthenticatedError creates an AuthenticationError for a request with missing or invalid credentials.
+func NewUnauthenticatedError(err error) *AuthenticationError {
+	return &AuthenticationError{Err: err}
+}
+
+// NewForbiddenError creates an AuthenticationError for a request with valid credentials, which lack the permission for
+// the operation.
+func NewForbiddenError(err error) *AuthenticationError {
+	return &AuthenticationError{Forbidden: true, Err: err}
+}
+
+// WithCode sets the error code added to Bearer challenges and returns the AuthenticationError.
+func (er *AuthenticationError) WithCode(code string) *AuthenticationError {
+	er.Code = code
+	return er
+}
+
+// implementation of error
+func (er *AuthenticationError) Error() string {
+	if er.Err == nil {
+		if er.Forbidden {
+			return "forbidden"
+		}
+		return "unauthenticated"
+	}
+	return er.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (er *AuthenticationError) Unwrap() error {
+	return er.Err
+}
+
+// schemeError attaches the name of the security scheme to the error of its authentication.
+type schemeError struct {
+	scheme string
+	err    error
+}
+
+// implementation of error
+func (er *schemeError) Error() string {
+	return er.err.Error()
+}
+
+// Unwrap returns the error of the authentication.
+func (er *schemeError) Unwrap() error {
+	return er.err
+}
+
+// Authentication describes how a request was authenticated. It is available to handler functions of operations with
+// security requirements by AuthenticationOf.
+type Authentication struct {
+	// the security requirement of the operation satisfied by the request, mapping the names of the security schemes to
+	// the required scopes
+	Requirement openapi3.SecurityRequirement
+	// the principals returned by the Authenticator of every security scheme of the requirement
+	Principals map[string]interface{}
+	// the principals of the successful authentications by the name of the security scheme and the required scopes
+	authenticated map[string]interface{}
+}
+
+// newAuthentication creates the Authentication of a request, which records the successful authentications.
+func newAuthentication() *Authentication {
+	return &Authentication{Principals: make(map[string]interface{}), authenticated: make(map[string]interface{})}
+}
+
+// scopedSchemeKey identifies the authentication of a security scheme with the scopes of a security requirement.
+func scopedSchemeKey(scheme string, scopes []string) string {
+	return scheme + " " + strings.Join(scopes, " ")
+}
+
+// AuthenticationOf returns the Authentication of a request handled by a Router. It returns nil, if the operation of
+// the request has no security requirements.
+func AuthenticationOf(request *http.Request) *Authentication {
+	authentication, ok := request.Context().Value(authenticationKey).(*Authentication)
+	if !ok || authentication.Requirement == nil {
+		return nil
+	}
+	return authentication
+}
+
+// Principal returns the principal of type T returned by an Authenticator for the request. If the satisfied security
+// requirement consists of multiple security schemes, the principal of the first scheme in alphabetical order matching
+// the type is returned. The second return value is false, if no such principal exists.
+func Principal[T any](request *http.Request) (T, bool) {
+	var result T
+	authentication := AuthenticationOf(request)
+	if authentication == nil {
+		return result, false
+	}
+	names := make([]string, 0, len(authentication.Principals))
+	for name := range authentication.Principals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if principal, ok := authentication.Principals[name].(T); ok {
+			return principal, true
+		}
+	}
+	return result, false
+}
+
+// satisfy determines the first security requirement whose security schemes were all authenticated successfully with
+// the scopes of the requirement. This is the requirement openapi3filter accepted, because it checks the requirements
+// in the same order and stops at the first one passing the authentication of all its schemes.
+func (authentication *Authentication) satisfy(requirements openapi3.SecurityRequirements) {
+	for _, requirement := range requirements {
+		principals := make(map[string]interface{}, len(requirement))
+		for name, scopes := range requirement {
+			principal, ok := authentication.authenticated[scopedSchemeKey(name, scopes)]
+			if !ok {
+				break
+			}
+			principals[name] = principal
+		}
+		if len(principals) == len(requirement) {
+			authentication.Requirement = requirement
+			authentication.Principals = principals
+			return
+		}
+	}
+}
+
+// recordPrincipal adapts an Authenticator to an openapi3filter.AuthenticationFunc. The principal of every security
+// scheme authenticated successfully is recorded with the required scopes in the Authentication of the request context.
+func recordPrincipal(authenticator Authenticator) openapi3filter.AuthenticationFunc {
+	return func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+		principal, err := authenticator(ctx, input)
+		if err == openapi3filter.ErrAuthenticationServiceMissing {
+			return err
+		}
+		if err != nil {
+			return &schemeError{scheme: input.SecuritySchemeName, err: err}
+		}
+		if authentication, ok := ctx.Value(authenticationKey).(*Authentication); ok {
+			authentication.authenticated[scopedSchemeKey(input.SecuritySchemeName, input.Scopes)] = principal
+		}
+		return nil
+	}
+}
+
+// RegisterSecurityScheme adds the Authenticator for the security scheme with the specified name in the
+// components.securitySchemes of the OpenAPI specification. It is used for every operation referencing the scheme,
+// either by its own security requirements or by the global security requirements of the specification. An
+// openapi3filter.AuthenticationFunc added with a request handler takes precedence over the registered authenticators.
+// The function panics, if the security scheme is not specified.
+func (router *Router) RegisterSecurityScheme(name string, authenticator Authenticator) {
+	if err := router.checkSecurityScheme(name); err != nil {
+		router.fail(err)
+	}
+	router.authenticators[name] = authenticator
+}
+
+// checkSecurityScheme returns an error, if the security scheme is not specified in the OpenAPI specification.
+func (router *Router) checkSecurityScheme(name string) error {
+	if router.swagger.Components == nil || router.swagger.Components.SecuritySchemes[name] == nil {
+		return fmt.Errorf("no security scheme with name %s is specified", name)
+	}
+	return nil
+}
+
+// authenticate is the Authenticator of every request handler added without its own function. It dispatches the
+// authentication to the Authenticator registered for the security scheme. Schemes without an Authenticator are checked
+// by the AuthenticationFunc of the validation options of the Router, if one is set.
+func (router *Router) authenticate(ctx context.Context, input *openapi3filter.AuthenticationInput) (interface{},
+	error) {
+	if authenticator, ok := router.authenticators[input.SecuritySchemeName]; ok {
+		return authenticator(ctx, input)
+	}
+	if router.options.AuthenticationFunc != nil {
+		return nil, router.options.AuthenticationFunc(ctx, input)
+	}
+	return nil, openapi3filter.ErrAuthenticationServiceMissing
+}
+
+// missingAuthenticators returns the names of all security schemes referenced by implemented operations, which
+// cannot be authenticated because neither an Authenticator nor an openapi3filter.AuthenticationFunc is available.
+func (router *Router) missingAuthenticators() []string {
+	if router.options.AuthenticationFunc != nil {
+		return nil
+	}
+	missing := make(map[string]bool)
+	for operation, handler := range router.implementations {
+		if !handler.dispatchesAuth {
+			continue
+		}
+		for _, requirement := range router.securityRequirements(operation) {
+			for name := range requirement {
+				if _, ok := router.authenticators[name]; !ok {
+					missing[name] = true
+				}
+			}
+		}
+	}
+	result := make([]string, 0, len(missing))
+	for name := range missing {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// securityRequirements returns the security requirements of the operation, which are the global security requirements
+// of the specification, unless the operation overrides them.
+func (router *Router) securityRequirements(operation *openapi3.Operation) openapi3.SecurityRequirements {
+	if operation.Security != nil {
+		return *operation.Security
+	}
+	return router.swagger.Security
+}
+
+// isForbidden returns whether every security requirement failed with a forbidden AuthenticationError.
+func isForbidden(securityErr *openapi3filter.SecurityRequirementsError) bool {
+	for _, err := range securityErr.Errors {
+		var authErr *AuthenticationError
+		if !errors.As(err, &authErr) || !authErr.Forbidden {
+			return false
+		}
+	}
+	return len(securityErr.Errors) > 0
+}
+
+// challenge creates the value of the WWW-Authenticate header according to RFC 7235 for a request, which failed the
+// security requirements. It contains a challenge for every security scheme of the type http with the scheme basic or
+// bearer, oauth2 and openIdConnect. The realm of the challenges is the title of the OpenAPI specification.
+func (router *Router) challenge(securityErr *openapi3filter.SecurityRequirementsError) string {
+	if router.swagger.Components == nil {
+		return ""
+	}
+	codes := make(map[string]string)
+	for _, err := range securityErr.Errors {
+		var schemeErr *schemeError
+		var authErr *AuthenticationError
+		if errors.As(err, &schemeErr) && errors.As(err, &authErr) && authErr.Code != "" {
+			codes[schemeErr.scheme] = authErr.Code
+		}
+	}
+	realm := ""
+	if router.swagger.Info != nil {
+		realm = router.swagger.Info.Title
+	}
+	var challenges []string
+	added := make(map[string]bool)
+	for _, requirement := range securityErr.SecurityRequirements {
+		names := make([]string, 0, len(requirement))
+		for name := range requirement {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			schemeRef := router.swagger.Components.SecuritySchemes[name]
+			if added[name] || schemeRef == nil || schemeRef.Value == nil {
+				continue
+			}
+			added[name] = true
+			scheme := schemeRef.Value
+			switch {
+			case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+				challenges = append(challenges, "Basic "+authParam("realm", realm))
+			case isBearerScheme(scheme):
+				params := []string{authParam("realm", realm)}
+				if scopes := requirement[name]; len(scopes) > 0 {
+					params = append(params, authParam("scope", strings.Join(scopes, " ")))
+				}
+				if code := codes[name]; code != "" {
+					params = append(params, authParam("error", code))
+				}
+				challenges = append(challenges, "Bearer "+strings.Join(params, ", "))
+			}
+		}
+	}
+	return strings.Join(challenges, ", ")
+}
+
+// authParam formats an auth-param of a challenge with a quoted value.
+func authParam(name string, value string) string {
+	return name + "=\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(value) + "\""
+}
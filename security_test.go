@@ -0,0 +1,481 @@
+package openapirouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const securityTestSpec = `
+openapi: 3.0.3
+info:
+  title: Security-API
+  version: 1.0.0
+security:
+  - apiKey: []
+paths:
+  /global:
+    get:
+      operationId: getGlobal
+      responses:
+        '204':
+          description: no content
+  /bearer:
+    get:
+      operationId: getBearer
+      security:
+        - bearer: []
+      responses:
+        '204':
+          description: no content
+  /combined:
+    get:
+      operationId: getCombined
+      security:
+        - bearer: []
+          apiKey: []
+        - oauth: [read]
+      responses:
+        '204':
+          description: no content
+  /scoped:
+    get:
+      operationId: getScoped
+      security:
+        - oauth: [admin]
+        - oauth: [read]
+      responses:
+        '204':
+          description: no content
+  /public:
+    get:
+      operationId: getPublic
+      security: []
+      responses:
+        '204':
+          description: no content
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      name: x-api-key
+      in: header
+    bearer:
+      type: http
+      scheme: bearer
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://example.com/token
+          scopes:
+            read: read access
+`
+
+type apiKeyPrincipal struct {
+	Tenant string
+}
+
+func apiKeyAuthenticator(_ context.Context, input *openapi3filter.AuthenticationInput) (interface{}, error) {
+	if input.RequestValidationInput.Request.Header.Get("x-api-key") != "secret" {
+		return nil, errors.New("invalid api key")
+	}
+	return &apiKeyPrincipal{Tenant: "tenant"}, nil
+}
+
+func getSecurityRouterAndServer(opts ...Option) (*Router, *httptest.Server) {
+	router, err := NewRouterFromData([]byte(securityTestSpec), opts...)
+	if err != nil {
+		panic(err)
+	}
+	for _, operationID := range []string{"getGlobal", "getBearer", "getCombined", "getScoped", "getPublic"} {
+		router.HandleOperation(operationID, handleNoContent)
+	}
+	return router, httptest.NewServer(router)
+}
+
+func TestRegisterSecurityScheme_GlobalSecurity(t *testing.T) {
+	testCases := []struct {
+		name     string
+		apiKey   string
+		expected int
+	}{
+		{"valid api key", "secret", http.StatusNoContent},
+		{"invalid api key", "wrong", http.StatusUnauthorized},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			router, server := getSecurityRouterAndServer()
+			defer server.Close()
+			router.RegisterSecurityScheme("apiKey", apiKeyAuthenticator)
+			request, _ := http.NewRequest(http.MethodGet, server.URL+"/global", nil)
+			request.Header.Set("x-api-key", testCase.apiKey)
+
+			// when
+			res, err := server.Client().Do(request)
+
+			// then
+			assert.Nil(t, err)
+			if assert.NotNil(t, res) {
+				assert.Equal(t, testCase.expected, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRegisterSecurityScheme_DispatchesByScheme(t *testing.T) {
+	// given
+	var schemes []string
+	router, server := getSecurityRouterAndServer(WithSecurityScheme("bearer",
+		func(_ context.Context, input *openapi3filter.AuthenticationInput) (interface{}, error) {
+			schemes = append(schemes, input.SecuritySchemeName)
+			return nil, nil
+		}))
+	defer server.Close()
+	router.RegisterSecurityScheme("apiKey", apiKeyAuthenticator)
+	router.RegisterSecurityScheme("oauth", apiKeyAuthenticator)
+
+	// when
+	res, err := server.Client().Get(server.URL + "/bearer")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	assert.Equal(t, []string{"bearer"}, schemes)
+	assert.Nil(t, router.Verify())
+}
+
+func TestRegisterSecurityScheme_MissingAuthenticator(t *testing.T) {
+	// given
+	router, server := getSecurityRouterAndServer()
+	defer server.Close()
+	router.RegisterSecurityScheme("apiKey", apiKeyAuthenticator)
+
+	// when
+	res, err := server.Client().Get(server.URL + "/bearer")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	}
+	if verificationErr, ok := router.Verify().(*VerificationError); assert.True(t, ok) {
+		assert.Equal(t, []string{"bearer", "oauth"}, verificationErr.MissingAuthenticators)
+	}
+}
+
+func TestRegisterSecurityScheme_WithoutRequirements(t *testing.T) {
+	// given
+	_, server := getSecurityRouterAndServer()
+	defer server.Close()
+
+	// when
+	res, err := server.Client().Get(server.URL + "/public")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+}
+
+func TestRegisterSecurityScheme_AuthFuncOfHandlerTakesPrecedence(t *testing.T) {
+	// given
+	router, server := getSecurityRouterAndServer()
+	defer server.Close()
+	router.RegisterSecurityScheme("apiKey", apiKeyAuthenticator)
+	router.HandleOperationWithAuthFunc("getGlobal", handleNoContent, openapi3filter.NoopAuthenticationFunc)
+
+	// when
+	res, err := server.Client().Get(server.URL + "/global")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+}
+
+func TestRegisterSecurityScheme_UnknownScheme(t *testing.T) {
+	// given
+	router, server := getSecurityRouterAndServer()
+	defer server.Close()
+
+	// when
+	register := func() { router.RegisterSecurityScheme("unknown", apiKeyAuthenticator) }
+
+	// then
+	assert.Panics(t, register)
+}
+
+func TestWithSecurityScheme_UnknownScheme(t *testing.T) {
+	// when
+	_, err := NewRouterFromData([]byte(securityTestSpec), WithSecurityScheme("unknown", apiKeyAuthenticator))
+
+	// then
+	assert.NotNil(t, err)
+}
+
+func TestPrincipal_ShouldBePassedToHandler(t *testing.T) {
+	// given
+	router, server := getSecurityRouterAndServer()
+	defer server.Close()
+	router.RegisterSecurityScheme("apiKey", apiKeyAuthenticator)
+	var principal *apiKeyPrincipal
+	var found bool
+	var authentication *Authentication
+	router.HandleOperation("getGlobal", func(request *http.Request, _ map[string]string) (*Response, error) {
+		principal, found = Principal[*apiKeyPrincipal](request)
+		authentication = AuthenticationOf(request)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/global", nil)
+	request.Header.Set("x-api-key", "secret")
+
+	// when
+	res, err := server.Client().Do(request)
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	assert.True(t, found)
+	assert.Equal(t, &apiKeyPrincipal{Tenant: "tenant"}, principal)
+	if assert.NotNil(t, authentication) {
+		assert.Equal(t, openapi3.SecurityRequirement{"apiKey": []string{}}, authentication.Requirement)
+	}
+}
+
+func TestPrincipal_ShouldReportSatisfiedRequirement(t *testing.T) {
+	// given
+	router, server := getSecurityRouterAndServer()
+	defer server.Close()
+	router.RegisterSecurityScheme("apiKey", apiKeyAuthenticator)
+	router.RegisterSecurityScheme("bearer", func(_ context.Context, _ *openapi3filter.AuthenticationInput) (
+		interface{}, error) {
+		return "bearer-user", nil
+	})
+	router.RegisterSecurityScheme("oauth", func(_ context.Context, _ *openapi3filter.AuthenticationInput) (
+		interface{}, error) {
+		return "oauth-client", nil
+	})
+	var authentication *Authentication
+	var principal string
+	router.HandleOperation("getCombined", func(request *http.Request, _ map[string]string) (*Response, error) {
+		authentication = AuthenticationOf(request)
+		principal, _ = Principal[string](request)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/combined")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	if assert.NotNil(t, authentication) {
+		assert.Equal(t, openapi3.SecurityRequirement{"oauth": []string{"read"}}, authentication.Requirement)
+		assert.Equal(t, map[string]interface{}{"oauth": "oauth-client"}, authentication.Principals)
+	}
+	assert.Equal(t, "oauth-client", principal)
+}
+
+func TestPrincipal_ShouldReportRequirementOfGrantedScopes(t *testing.T) {
+	// given
+	router, server := getSecurityRouterAndServer()
+	defer server.Close()
+	router.RegisterSecurityScheme("oauth", func(_ context.Context, input *openapi3filter.AuthenticationInput) (
+		interface{}, error) {
+		for _, scope := range input.Scopes {
+			if scope != "read" {
+				return nil, NewForbiddenError(fmt.Errorf("scope %s is not granted", scope))
+			}
+		}
+		return "oauth-client", nil
+	})
+	var authentication *Authentication
+	router.HandleOperation("getScoped", func(request *http.Request, _ map[string]string) (*Response, error) {
+		authentication = AuthenticationOf(request)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/scoped")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	if assert.NotNil(t, authentication) {
+		assert.Equal(t, openapi3.SecurityRequirement{"oauth": []string{"read"}}, authentication.Requirement)
+		assert.Equal(t, map[string]interface{}{"oauth": "oauth-client"}, authentication.Principals)
+	}
+}
+
+func TestPrincipal_WithoutSecurityRequirements(t *testing.T) {
+	// given
+	router, server := getSecurityRouterAndServer()
+	defer server.Close()
+	found := true
+	var authentication *Authentication
+	router.HandleOperation("getPublic", func(request *http.Request, _ map[string]string) (*Response, error) {
+		_, found = Principal[*apiKeyPrincipal](request)
+		authentication = AuthenticationOf(request)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/public")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	assert.False(t, found)
+	assert.Nil(t, authentication)
+}
+
+const challengeTestSpec = `
+openapi: 3.0.3
+info:
+  title: Challenge "API"
+  version: 1.0.0
+paths:
+  /basic:
+    get:
+      operationId: getBasic
+      security:
+        - basic: []
+      responses:
+        '204':
+          description: no content
+  /mixed:
+    get:
+      operationId: getMixed
+      security:
+        - bearer: []
+        - oauth: [read, write]
+          basic: []
+      responses:
+        '204':
+          description: no content
+components:
+  securitySchemes:
+    basic:
+      type: http
+      scheme: basic
+    bearer:
+      type: http
+      scheme: bearer
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://example.com/token
+          scopes:
+            read: read access
+            write: write access
+`
+
+func getChallengeRouterAndServer(authenticator Authenticator) (*Router, *httptest.Server) {
+	router, err := NewRouterFromData([]byte(challengeTestSpec))
+	if err != nil {
+		panic(err)
+	}
+	for _, scheme := range []string{"basic", "bearer", "oauth"} {
+		router.RegisterSecurityScheme(scheme, authenticator)
+	}
+	router.HandleOperation("getBasic", handleNoContent)
+	router.HandleOperation("getMixed", handleNoContent)
+	return router, httptest.NewServer(router)
+}
+
+func TestAuthenticationError_StatusAndChallenge(t *testing.T) {
+	testCases := []struct {
+		name              string
+		path              string
+		err               error
+		expectedStatus    int
+		expectedChallenge string
+	}{
+		{"basic unauthenticated", "/basic", errors.New("no credentials"), http.StatusUnauthorized,
+			`Basic realm="Challenge \"API\""`},
+		{"basic forbidden", "/basic", NewForbiddenError(errors.New("not permitted")), http.StatusForbidden,
+			`Basic realm="Challenge \"API\""`},
+		{"bearer unauthenticated", "/mixed", NewUnauthenticatedError(nil), http.StatusUnauthorized,
+			`Bearer realm="Challenge \"API\"", Basic realm="Challenge \"API\"", ` +
+				`Bearer realm="Challenge \"API\"", scope="read write"`},
+		{"bearer invalid token", "/mixed", NewUnauthenticatedError(nil).WithCode("invalid_token"),
+			http.StatusUnauthorized, `Bearer realm="Challenge \"API\"", error="invalid_token", ` +
+				`Basic realm="Challenge \"API\"", Bearer realm="Challenge \"API\"", scope="read write"`},
+		{"bearer insufficient scope", "/mixed", NewForbiddenError(nil).WithCode("insufficient_scope"),
+			http.StatusForbidden, `Bearer realm="Challenge \"API\"", error="insufficient_scope", ` +
+				`Basic realm="Challenge \"API\"", Bearer realm="Challenge \"API\"", scope="read write"`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			_, server := getChallengeRouterAndServer(func(_ context.Context, input *openapi3filter.AuthenticationInput) (
+				interface{}, error) {
+				return nil, testCase.err
+			})
+			defer server.Close()
+
+			// when
+			res, err := server.Client().Get(server.URL + testCase.path)
+
+			// then
+			assert.Nil(t, err)
+			if assert.NotNil(t, res) {
+				assert.Equal(t, testCase.expectedStatus, res.StatusCode)
+				assert.Equal(t, testCase.expectedChallenge, res.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAuthenticationError_ForbiddenOnlyIfAllRequirementsForbid(t *testing.T) {
+	// given
+	_, server := getChallengeRouterAndServer(func(_ context.Context, input *openapi3filter.AuthenticationInput) (
+		interface{}, error) {
+		if input.SecuritySchemeName == "bearer" {
+			return nil, NewForbiddenError(nil)
+		}
+		return nil, NewUnauthenticatedError(nil)
+	})
+	defer server.Close()
+
+	// when
+	res, err := server.Client().Get(server.URL + "/mixed")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+func TestAuthenticationError_Unwrap(t *testing.T) {
+	// given
+	err := NewForbiddenError(ErrInsufficientScope)
+
+	// then
+	assert.True(t, errors.Is(err, ErrInsufficientScope))
+	assert.Equal(t, "insufficient scope", err.Error())
+	assert.Equal(t, "forbidden", NewForbiddenError(nil).Error())
+	assert.Equal(t, "unauthenticated", NewUnauthenticatedError(nil).Error())
+}
@@ -0,0 +1,112 @@
+package openapirouter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Attachment returns a Response with the status code http.StatusOK, which streams the body to the client as a file
+// download with the file name. The media type of the body is negotiated from the media types documented for the
+// operation, or application/octet-stream is used without documented media types. Set the Content-Type in the headers
+// of the Response to specify another one. The body is closed after it was written, if it implements io.Closer.
+func Attachment(filename string, body io.Reader) *Response {
+	return &Response{
+		StatusCode: http.StatusOK,
+		Body:       body,
+		Headers:    map[string]string{"Content-Disposition": ContentDisposition("attachment", filename)},
+	}
+}
+
+// ContentDisposition returns the value of the Content-Disposition header with the disposition type, i.e. attachment or
+// inline, and the file name. File names with characters outside of ASCII are encoded as specified in RFC 2231.
+func ContentDisposition(disposition string, filename string) string {
+	return mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+}
+
+// isStream returns whether the Body of a Response is streamed to the client as is instead of being encoded.
+func isStream(body interface{}) bool {
+	switch body.(type) {
+	case io.Reader, []byte:
+		return true
+	default:
+		return false
+	}
+}
+
+// stream writes the Response with the streamed Body copied to the client as is. The Content-Type is set unless it is
+// specified in the headers of the Response, and the Content-Length is set, if the size of the Body is known.
+// Since the status code is already sent, when the Body fails to be read, the error is only returned. A responseBuffer
+// does not buffer the Body, but provides the http.ResponseWriter of the client, unless the response was replaced
+// because of an invalid status code or headers.
+func (response *Response) stream(writer http.ResponseWriter, contentType string) error {
+	var body io.Reader
+	switch value := response.Body.(type) {
+	case []byte:
+		body = bytes.NewReader(value)
+	case io.Reader:
+		body = value
+	}
+	setDefaultContentType(writer, contentType)
+	if size, ok := contentLength(body); ok {
+		writer.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	for key, value := range response.Headers {
+		writer.Header().Set(key, value)
+	}
+	writer.WriteHeader(response.StatusCode)
+	if buffer, ok := writer.(*responseBuffer); ok && buffer.stream != nil {
+		buffer.streamed = true
+		if writer = buffer.stream(buffer); writer == nil {
+			return nil
+		}
+	}
+	_, err := io.Copy(writer, body)
+	return err
+}
+
+// contentLength returns the number of bytes left in the body, if it is known. This is the case for in-memory readers
+// like bytes.Reader and for seekable readers like regular files.
+func contentLength(body io.Reader) (int64, bool) {
+	if sized, ok := body.(interface{ Len() int }); ok {
+		return int64(sized.Len()), true
+	}
+	seeker, ok := body.(io.Seeker)
+	if !ok {
+		return 0, false
+	}
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false
+	}
+	if _, err = seeker.Seek(current, io.SeekStart); err != nil {
+		return 0, false
+	}
+	return end - current, true
+}
+
+// closeOnDone closes the body, if it implements io.Closer, as soon as the context is done, so copying a blocked body,
+// e.g. a proxied stream, is aborted when the client disconnects. The returned function closes the
+// body, unless it was already closed, and returns the error of closing it.
+func closeOnDone(ctx context.Context, body interface{}) func() error {
+	closer, ok := body.(io.Closer)
+	if !ok {
+		return func() error { return nil }
+	}
+	closeBody := sync.OnceValue(closer.Close)
+	stop := context.AfterFunc(ctx, func() {
+		_ = closeBody()
+	})
+	return func() error {
+		stop()
+		return closeBody()
+	}
+}
@@ -0,0 +1,236 @@
+package openapirouter
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const streamTestSpec = `
+openapi: 3.0.3
+info:
+  title: Stream-API
+  version: 1.0.0
+paths:
+  /documents/{id}:
+    get:
+      operationId: getDocument
+      parameters:
+        - in: path
+          name: id
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: document
+          content:
+            application/pdf:
+              schema:
+                type: string
+                format: binary
+            text/plain:
+              schema:
+                type: string
+  /events:
+    get:
+      operationId: getEvents
+      responses:
+        '200':
+          description: events
+`
+
+// closeRecorder is an io.ReadCloser, which records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (recorder *closeRecorder) Close() error {
+	recorder.closed = true
+	return nil
+}
+
+func getStreamRouter(body func() interface{}) *Router {
+	router, err := NewRouterFromData([]byte(streamTestSpec))
+	if err != nil {
+		panic(err)
+	}
+	router.HandleOperation("getDocument", func(_ *http.Request, pathParams map[string]string) (*Response, error) {
+		return Attachment(pathParams["id"]+".pdf", bytes.NewReader([]byte("%PDF-1.7"))), nil
+	})
+	router.HandleOperation("getEvents", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{StatusCode: http.StatusOK, Body: body()}, nil
+	})
+	return router
+}
+
+func TestStream_ShouldWriteAttachment(t *testing.T) {
+	// given
+	router := getStreamRouter(nil)
+
+	// when
+	recorder := serveWithAccept(router, "/documents/contract", "application/pdf")
+
+	// then
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/pdf", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "8", recorder.Header().Get("Content-Length"))
+	assert.Equal(t, `attachment; filename=contract.pdf`, recorder.Header().Get("Content-Disposition"))
+	assert.Equal(t, "%PDF-1.7", recorder.Body.String())
+}
+
+func TestStream_ShouldWriteBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          interface{}
+		contentLength string
+	}{
+		{"bytes", []byte("data"), "4"},
+		{"reader with length", strings.NewReader("data"), "4"},
+		{"reader without length", io.MultiReader(strings.NewReader("da"), strings.NewReader("ta")), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router := getStreamRouter(func() interface{} { return test.body })
+
+			// when
+			recorder := serveWithAccept(router, "/events")
+
+			// then
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "application/octet-stream", recorder.Header().Get("Content-Type"))
+			assert.Equal(t, test.contentLength, recorder.Header().Get("Content-Length"))
+			assert.Equal(t, "data", recorder.Body.String())
+		})
+	}
+}
+
+func TestStream_ShouldCloseBody(t *testing.T) {
+	// given
+	body := &closeRecorder{Reader: strings.NewReader("data")}
+	router := getStreamRouter(func() interface{} { return body })
+
+	// when
+	recorder := serveWithAccept(router, "/events")
+
+	// then
+	assert.Equal(t, "data", recorder.Body.String())
+	assert.True(t, body.closed)
+}
+
+func TestStream_ShouldCloseBodyOnDisconnect(t *testing.T) {
+	// given
+	reader, writer := io.Pipe()
+	router := getStreamRouter(func() interface{} { return reader })
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	served := make(chan struct{})
+	go func() {
+		router.ServeHTTP(httptest.NewRecorder(), request)
+		close(served)
+	}()
+	_, _ = writer.Write([]byte("event"))
+
+	// when
+	cancel()
+
+	// then
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("response was not aborted")
+	}
+	_, err := writer.Write([]byte("event"))
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+func TestStream_NotAcceptable(t *testing.T) {
+	// given
+	router := getStreamRouter(nil)
+
+	// when
+	recorder := serveWithAccept(router, "/documents/contract", "application/json")
+
+	// then
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "supported media types: application/pdf, text/plain")
+}
+
+func TestStream_ShouldValidateResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		expected    int
+	}{
+		{"documented media type", "application/pdf", http.StatusOK},
+		{"undocumented media type", "image/png", http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router := getStreamRouter(nil)
+			router.SetResponseValidationMode(ResponseValidationFail)
+			router.HandleOperation("getDocument", func(_ *http.Request, _ map[string]string) (*Response, error) {
+				response := Attachment("contract.pdf", strings.NewReader("%PDF-1.7"))
+				response.Headers["Content-Type"] = test.contentType
+				return response, nil
+			})
+
+			// when
+			recorder := serveWithAccept(router, "/documents/contract")
+
+			// then
+			assert.Equal(t, test.expected, recorder.Code)
+		})
+	}
+}
+
+// observingReader returns a single chunk and records the size of the response body written so far on the next read.
+type observingReader struct {
+	chunk    []byte
+	recorder *httptest.ResponseRecorder
+	written  int
+}
+
+func (reader *observingReader) Read(data []byte) (int, error) {
+	if reader.chunk == nil {
+		reader.written = reader.recorder.Body.Len()
+		return 0, io.EOF
+	}
+	size := copy(data, reader.chunk)
+	reader.chunk = nil
+	return size, nil
+}
+
+func TestStream_ShouldNotBufferValidatedResponse(t *testing.T) {
+	// given
+	recorder := httptest.NewRecorder()
+	body := &observingReader{chunk: []byte("%PDF-1.7"), recorder: recorder}
+	router := getStreamRouter(nil)
+	router.SetResponseValidationMode(ResponseValidationFail)
+	router.HandleOperation("getDocument", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return Attachment("contract.pdf", body), nil
+	})
+
+	// when
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/documents/contract", nil))
+
+	// then
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/pdf", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "%PDF-1.7", recorder.Body.String())
+	assert.Equal(t, 8, body.written)
+}
+
+func TestContentDisposition(t *testing.T) {
+	assert.Equal(t, `attachment; filename="annual report.pdf"`, ContentDisposition("attachment", "annual report.pdf"))
+	assert.Equal(t, `inline; filename*=utf-8''%C3%BCbersicht.pdf`, ContentDisposition("inline", "übersicht.pdf"))
+}
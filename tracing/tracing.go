@@ -0,0 +1,95 @@
+// Package tracing traces the requests served by an openapirouter.Router with OpenTelemetry.
+package tracing
+
+import (
+	"context"
+	"github.com/huk-coburg/openapirouter"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// tracerName is the name of the instrumentation library passed to the trace.TracerProvider.
+const tracerName = "github.com/huk-coburg/openapirouter"
+
+// attribute keys of the spans, which are not defined by the semantic conventions
+const (
+	operationIDKey = attribute.Key("openapi.operation_id")
+	tagsKey        = attribute.Key("openapi.tags")
+	rejectionKey   = attribute.Key("openapi.rejection")
+)
+
+// tracing records the spans of the requests served by a Router.
+type tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracing traces all requests served by the Router with OpenTelemetry. For every request, a server span named after
+// the operationId of the matched operation, or its path template if the operation has no operationId, is started. The
+// span continues the trace of the W3C trace context headers of the request and contains the route of the operation as
+// attributes. The validation of the request and the handler function are recorded as child spans. Responses with a
+// status code of 500 or above set the status of the span to error. If the trace.TracerProvider is nil, the global
+// provider of the otel package is used.
+func WithTracing(provider trace.TracerProvider) openapirouter.Option {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	tracing := &tracing{
+		tracer: provider.Tracer(tracerName, trace.WithSchemaURL(semconv.SchemaURL)),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{},
+			propagation.Baggage{}),
+	}
+	instrumentation := openapirouter.WithInstrumentation(tracing.trace)
+	phaseTracer := openapirouter.WithPhaseTracer(tracing.startPhase)
+	return func(router *openapirouter.Router) {
+		instrumentation(router)
+		phaseTracer(router)
+	}
+}
+
+// trace wraps the handling of a request to record its server span.
+func (tracing *tracing) trace(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := tracing.propagator.Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+		name := "HTTP " + request.Method
+		attributes := []attribute.KeyValue{semconv.HTTPMethodKey.String(request.Method)}
+		if info, ok := openapirouter.RouteInfoFromContext(ctx); ok {
+			name = info.OperationID
+			if name == "" {
+				name = info.Method + " " + info.PathTemplate
+			}
+			attributes = append(attributes, semconv.HTTPRouteKey.String(info.PathTemplate),
+				operationIDKey.String(info.OperationID), tagsKey.StringSlice(info.Tags))
+		}
+		ctx, span := tracing.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attributes...))
+		defer span.End()
+		recorder := openapirouter.NewStatusRecorder(writer)
+		handler.ServeHTTP(recorder, request.WithContext(ctx))
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(recorder.Status()))
+		if reason, _ := openapirouter.RejectionFromContext(ctx); reason != "" {
+			span.SetAttributes(rejectionKey.String(reason))
+		}
+		if recorder.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.Status()))
+		}
+	})
+}
+
+// startPhase starts a child span of the server span of the request for a phase of its handling. The span records the
+// error of the phase and sets its status to error.
+func (tracing *tracing) startPhase(ctx context.Context, name string) (context.Context, func(err error)) {
+	ctx, span := tracing.tracer.Start(ctx, name)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,163 @@
+package tracing
+
+import (
+	"errors"
+	"github.com/huk-coburg/openapirouter"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func handleNoContent(_ *http.Request, _ map[string]string) (*openapirouter.Response, error) {
+	return &openapirouter.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+func getTracingRouter() (*openapirouter.Router, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	router, err := openapirouter.NewRouter("../testdata/test-api.yaml", WithTracing(provider))
+	if err != nil {
+		panic(err)
+	}
+	return router, exporter
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+func TestTracing_ShouldRecordServerSpan(t *testing.T) {
+	// given
+	router, exporter := getTracingRouter()
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (
+		*openapirouter.Response, error) {
+		return &openapirouter.Response{StatusCode: http.StatusOK, Body: map[string]string{"data": "test"}}, nil
+	})
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	// then
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	spans := exporter.GetSpans()
+	if assert.Equal(t, []string{"validate request", "handle request", "getTestData"}, spanNames(spans)) {
+		server := spans[2]
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+		assert.Equal(t, codes.Unset, server.Status.Code)
+		attributes := spanAttributes(server)
+		assert.Equal(t, "GET", attributes["http.method"].AsString())
+		assert.Equal(t, "/test", attributes["http.route"].AsString())
+		assert.Equal(t, "getTestData", attributes[operationIDKey].AsString())
+		assert.Equal(t, int64(http.StatusOK), attributes["http.status_code"].AsInt64())
+		assert.NotContains(t, attributes, rejectionKey)
+		for _, child := range spans[:2] {
+			assert.Equal(t, server.SpanContext.SpanID(), child.Parent.SpanID())
+			assert.Equal(t, server.SpanContext.TraceID(), child.SpanContext.TraceID())
+		}
+	}
+}
+
+func TestTracing_ShouldContinueTraceContext(t *testing.T) {
+	// given
+	router, exporter := getTracingRouter()
+	router.HandleOperation("getTestData", handleNoContent)
+	request := httptest.NewRequest(http.MethodGet, "/test", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	// when
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	// then
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 3) {
+		server := spans[2]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+		assert.True(t, server.Parent.IsRemote())
+	}
+}
+
+func TestTracing_ShouldRecordHandlerErrors(t *testing.T) {
+	// given
+	router, exporter := getTracingRouter()
+	router.HandleOperation("getTestData", func(_ *http.Request, _ map[string]string) (
+		*openapirouter.Response, error) {
+		return nil, errors.New("database unavailable")
+	})
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	// then
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	spans := exporter.GetSpans()
+	if assert.Equal(t, []string{"validate request", "handle request", "getTestData"}, spanNames(spans)) {
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+		assert.Equal(t, codes.Error, spans[1].Status.Code)
+		if assert.Len(t, spans[1].Events, 1) {
+			assert.Equal(t, "exception", spans[1].Events[0].Name)
+		}
+		assert.Equal(t, codes.Error, spans[2].Status.Code)
+		assert.Equal(t, int64(http.StatusInternalServerError),
+			spanAttributes(spans[2])["http.status_code"].AsInt64())
+	}
+}
+
+func TestTracing_ShouldRecordRejections(t *testing.T) {
+	// given
+	router, exporter := getTracingRouter()
+	router.HandleOperation("getQuery", handleNoContent)
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test/query", nil))
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	spans := exporter.GetSpans()
+	if assert.Equal(t, []string{"validate request", "getQuery"}, spanNames(spans)) {
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.Equal(t, codes.Unset, spans[1].Status.Code)
+		attributes := spanAttributes(spans[1])
+		assert.Equal(t, openapirouter.RejectionInvalidRequest, attributes[rejectionKey].AsString())
+		assert.Equal(t, int64(http.StatusBadRequest), attributes["http.status_code"].AsInt64())
+	}
+}
+
+func TestTracing_UnmatchedRequest(t *testing.T) {
+	// given
+	router, exporter := getTracingRouter()
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	// then
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	spans := exporter.GetSpans()
+	if assert.Equal(t, []string{"HTTP GET"}, spanNames(spans)) {
+		attributes := spanAttributes(spans[0])
+		assert.NotContains(t, attributes, attribute.Key("http.route"))
+		assert.Equal(t, openapirouter.RejectionNotFound, attributes[rejectionKey].AsString())
+	}
+}
@@ -0,0 +1,88 @@
+package openapirouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// Params gives a typed handler function access to the request beyond its decoded body.
+type Params struct {
+	// path parameters of the request
+	Path map[string]string
+	// the underlying http.Request, e.g. to read headers or query parameters
+	Request *http.Request
+}
+
+// Handle adds a typed handler function for the operation with the specified operationId. The request body is decoded
+// into a value of type In with BindBody before the function is invoked. Since the request was already validated against
+// the OpenAPI specification, the function does not need to check the body against the schema again. The output of the
+// function is written with the documented success status of the operation, i.e. the lowest 2xx status code of its
+// responses. If this response does not document any content or the output is a nil pointer, no body is written.
+// The function may return a *Response as output to set the status and headers explicitly. An error returned by the
+// function is mapped by the error mapper of the Router, like the error of a HandleRequestFunction. So is an error of
+// BindBody, e.g. because the body does not fit into In, which results in an Internal Server Error unless it is mapped.
+// The function panics, if no operation with the operationId is specified in the OpenAPI specification.
+func Handle[In, Out any](router *Router, operationID string,
+	handleFunc func(context.Context, In, Params) (Out, error)) {
+	operation, ok := router.operations[operationID]
+	if !ok {
+		router.fail(fmt.Errorf("no operation with operationId %s is specified", operationID))
+	}
+	statusCode, hasContent := successResponse(operation)
+	router.HandleOperation(operationID, func(request *http.Request, pathParams map[string]string) (*Response, error) {
+		var input In
+		if err := BindBody(request, &input); err != nil {
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("request body could not be decoded: %w", err)
+		}
+		output, err := handleFunc(request.Context(), input, Params{Path: pathParams, Request: request})
+		if err != nil {
+			return nil, err
+		}
+		if response, ok := any(output).(*Response); ok && response != nil {
+			return response, nil
+		}
+		response := &Response{StatusCode: statusCode}
+		if hasContent && !isNilPointer(output) {
+			response.Body = output
+		}
+		return response, nil
+	})
+}
+
+// isNilPointer returns whether the value is a nil pointer.
+func isNilPointer(value any) bool {
+	reflected := reflect.ValueOf(value)
+	return reflected.Kind() == reflect.Pointer && reflected.IsNil()
+}
+
+// successResponse returns the lowest 2xx status code documented for the operation and whether this response has any
+// content. The range 2XX is only used with http.StatusOK, if no explicit 2xx status code is documented. If neither is
+// documented, http.StatusOK is used.
+func successResponse(operation *openapi3.Operation) (int, bool) {
+	statusCode := 0
+	hasContent := false
+	for key, response := range operation.Responses {
+		code, err := strconv.Atoi(key)
+		if err != nil || code < 200 || code > 299 || (statusCode != 0 && code >= statusCode) {
+			continue
+		}
+		statusCode = code
+		hasContent = response.Value != nil && len(response.Value.Content) > 0
+	}
+	if statusCode != 0 {
+		return statusCode, hasContent
+	}
+	if response := operation.Responses["2XX"]; response != nil {
+		return http.StatusOK, response.Value != nil && len(response.Value.Content) > 0
+	}
+	return http.StatusOK, true
+}
@@ -0,0 +1,198 @@
+package openapirouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestHandle_ShouldDecodeBody(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	var received TestData
+	Handle(router, "postTestData", func(_ context.Context, data TestData, _ Params) (struct{}, error) {
+		received = data
+		return struct{}{}, nil
+	})
+	dataBytes, _ := json.Marshal(TestData{Data: "test"})
+
+	// when
+	res, err := server.Client().Post(server.URL+"/test", "application/json", bytes.NewReader(dataBytes))
+
+	// then
+	assert.Nil(t, err)
+	assert.Equal(t, TestData{Data: "test"}, received)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Empty(t, res.Header.Get("Content-Type"))
+	}
+}
+
+func TestHandle_ShouldWriteOutputWithSuccessStatus(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	Handle(router, "getPathParams", func(_ context.Context, _ struct{}, params Params) (*TestData, error) {
+		return &TestData{Data: params.Path["param"]}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test/pathParams/value1")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		var data TestData
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&data))
+		assert.Equal(t, TestData{Data: "value1"}, data)
+	}
+}
+
+func TestHandle_ShouldWriteNoBodyForNilOutput(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	Handle(router, "getPathParams", func(_ context.Context, _ struct{}, _ Params) (*TestData, error) {
+		return nil, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test/pathParams/value1")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		body, _ := io.ReadAll(res.Body)
+		assert.Empty(t, body)
+	}
+}
+
+func TestHandle_ShouldPassThroughResponse(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	Handle(router, "getTestData", func(_ context.Context, _ struct{}, _ Params) (*Response, error) {
+		return &Response{StatusCode: http.StatusAccepted, Headers: map[string]string{"X-TEST": "test"}}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+		assert.Equal(t, "test", res.Header.Get("X-TEST"))
+	}
+}
+
+func TestHandle_ShouldMapErrors(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.AddErrorMapping(&ExampleError{}, http.StatusBadGateway)
+	Handle(router, "getTestData", func(_ context.Context, _ struct{}, _ Params) (*TestData, error) {
+		return nil, &ExampleError{}
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	}
+}
+
+func TestHandle_ShouldMapBindErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapped   bool
+		expected int
+	}{
+		{"unmapped", false, http.StatusInternalServerError},
+		{"mapped", true, http.StatusUnprocessableEntity},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, server := getRouterAndServer()
+			defer server.Close()
+			if test.mapped {
+				MapError(router, func(err *json.UnmarshalTypeError) *HTTPError {
+					return NewHTTPError(http.StatusUnprocessableEntity, err.Field)
+				})
+			}
+			calls := 0
+			Handle(router, "postTestData", func(_ context.Context, _ struct {
+				Data int `json:"data"`
+			}, _ Params) (struct{}, error) {
+				calls++
+				return struct{}{}, nil
+			})
+			dataBytes, _ := json.Marshal(TestData{Data: "test"})
+
+			// when
+			res, err := server.Client().Post(server.URL+"/test", "application/json", bytes.NewReader(dataBytes))
+
+			// then
+			assert.Nil(t, err)
+			assert.Equal(t, 0, calls)
+			if assert.NotNil(t, res) {
+				assert.Equal(t, test.expected, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandle_UnknownOperationID(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+
+	// when + then
+	assert.Panics(t, func() {
+		Handle(router, "unknownOperation", func(_ context.Context, _ struct{}, _ Params) (struct{}, error) {
+			return struct{}{}, nil
+		})
+	})
+}
+
+func TestSuccessResponse(t *testing.T) {
+	// given
+	withContent := &openapi3.ResponseRef{Value: openapi3.NewResponse().WithJSONSchema(openapi3.NewStringSchema())}
+	withoutContent := &openapi3.ResponseRef{Value: openapi3.NewResponse()}
+
+	// when
+	createdStatus, createdContent := successResponse(&openapi3.Operation{
+		Responses: openapi3.Responses{"400": withContent, "202": withoutContent, "201": withContent},
+	})
+	rangeStatus, rangeContent := successResponse(&openapi3.Operation{
+		Responses: openapi3.Responses{"2XX": withoutContent, "default": withContent},
+	})
+	explicitStatus, explicitContent := successResponse(&openapi3.Operation{
+		Responses: openapi3.Responses{"2XX": withoutContent, "201": withContent},
+	})
+	defaultStatus, defaultContent := successResponse(&openapi3.Operation{
+		Responses: openapi3.Responses{"default": withoutContent},
+	})
+
+	// then
+	assert.Equal(t, http.StatusCreated, createdStatus)
+	assert.True(t, createdContent)
+	assert.Equal(t, http.StatusOK, rangeStatus)
+	assert.False(t, rangeContent)
+	assert.Equal(t, http.StatusCreated, explicitStatus)
+	assert.True(t, explicitContent)
+	assert.Equal(t, http.StatusOK, defaultStatus)
+	assert.True(t, defaultContent)
+}
@@ -0,0 +1,460 @@
+package openapirouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+)
+
+// defaultMaxMemory is the number of bytes of uploaded files kept in memory, if UploadLimits.MaxMemory is not set. It is
+// the same default as the one of http.Request.ParseMultipartForm.
+const defaultMaxMemory = 32 << 20
+
+// maxValuesSize is the number of bytes of all parts, which are not files. Since values are kept in memory, they are
+// limited to 10 MB in total, like the values read by http.Request.ParseMultipartForm without a memory for files.
+const maxValuesSize = 10 << 20
+
+// defaultMaxParts is the number of parts of a multipart/form-data request body, if UploadLimits.MaxParts is not set. It
+// is the same default as the one of multipart.Reader.ReadForm.
+const defaultMaxParts = 1000
+
+// UploadLimits restricts the size of multipart/form-data request bodies. Requests exceeding a limit are rejected with
+// 413 Request Entity Too Large. A limit of 0 means no limit, except for the number of parts and for parts, which are
+// not files.
+type UploadLimits struct {
+	// maximum number of bytes of each part. The maxLength of the schema of the part takes precedence, if it is lower.
+	// Parts, which are not files, are limited to 10 MB in total.
+	MaxPartSize int64
+	// maximum number of bytes of the whole request body
+	MaxTotalSize int64
+	// number of bytes of all files which are kept in memory. Files exceeding it are spooled to temporary files. The
+	// default is 32 MB.
+	MaxMemory int64
+	// maximum number of parts of the request body. The default is 1000.
+	MaxParts int
+}
+
+// WithUploadLimits sets the UploadLimits for multipart/form-data request bodies. By default, the size of uploads is
+// only limited for values, which are not files, and the number of parts to 1000.
+func WithUploadLimits(limits UploadLimits) Option {
+	return func(router *Router) {
+		router.uploadLimits = limits
+	}
+}
+
+// Upload contains the fields and files of a multipart/form-data request body. The files of the Upload are removed
+// after the handler function returned, so they must not be used afterwards.
+type Upload struct {
+	// Values of the parts, which are not files, by the name of the part
+	Values map[string][]string
+	// Files of the parts with a binary schema or, without a schema, with a file name by the name of the part
+	Files map[string][]*UploadedFile
+}
+
+// UploadedFile is a file of an Upload. Small files are kept in memory, larger ones are spooled to a temporary file.
+type UploadedFile struct {
+	// Filename of the part, which may be empty
+	Filename string
+	// Header of the part
+	Header textproto.MIMEHeader
+	// Size of the file in bytes
+	Size int64
+	// content of the file, if it is kept in memory
+	content []byte
+	// path of the temporary file, if the file is spooled to disk
+	path string
+}
+
+// UploadOf returns the Upload of a multipart/form-data request handled by a Router. It is nil for other requests.
+func UploadOf(request *http.Request) *Upload {
+	upload, _ := request.Context().Value(uploadKey).(*Upload)
+	return upload
+}
+
+// Upload returns the Upload of the request. See UploadOf.
+func (params Params) Upload() *Upload {
+	return UploadOf(params.Request)
+}
+
+// Value returns the first value of the part with the name, or an empty string, if there is no such part.
+func (upload *Upload) Value(name string) string {
+	if values := upload.Values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// File returns the first file of the part with the name, or nil, if there is no such part.
+func (upload *Upload) File(name string) *UploadedFile {
+	if files := upload.Files[name]; len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+// RemoveAll removes all temporary files of the Upload.
+func (upload *Upload) RemoveAll() error {
+	var errs []error
+	for _, files := range upload.Files {
+		for _, file := range files {
+			if file.path != "" {
+				if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Open returns the content of the file. The multipart.File has to be closed by the caller.
+func (file *UploadedFile) Open() (multipart.File, error) {
+	if file.path != "" {
+		return os.Open(file.path)
+	}
+	return sectionReadCloser{io.NewSectionReader(bytes.NewReader(file.content), 0, int64(len(file.content)))}, nil
+}
+
+// sectionReadCloser implements multipart.File for a file in memory.
+type sectionReadCloser struct {
+	*io.SectionReader
+}
+
+// Close does nothing, since the file is kept in memory.
+func (sectionReadCloser) Close() error {
+	return nil
+}
+
+// uploadTooLargeError is returned if a multipart/form-data request body exceeds the UploadLimits.
+type uploadTooLargeError struct {
+	// name of the part exceeding the limit, empty if the whole body exceeds the limit
+	part  string
+	limit int64
+	// unit of the limit, which is bytes if it is empty
+	unit string
+}
+
+// Error returns the limit which was exceeded.
+func (err *uploadTooLargeError) Error() string {
+	unit := err.unit
+	if unit == "" {
+		unit = "bytes"
+	}
+	if err.part == "" {
+		return fmt.Sprintf("request body exceeds the limit of %d %s", err.limit, unit)
+	}
+	return fmt.Sprintf("part %s exceeds the limit of %d %s", err.part, err.limit, unit)
+}
+
+// uploadContent returns the documented multipart/form-data content of the request body, if the request is an upload.
+func uploadContent(request *http.Request, operation *openapi3.Operation) *openapi3.MediaType {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return nil
+	}
+	contentType := request.Header.Get("Content-Type")
+	if normalizeMediaType(contentType) != "multipart/form-data" {
+		return nil
+	}
+	content := operation.RequestBody.Value.Content.Get(contentType)
+	if content == nil || content.Schema == nil || content.Schema.Value == nil {
+		return nil
+	}
+	return content
+}
+
+// readUpload reads the multipart/form-data body of the request within the UploadLimits and validates it against the
+// schema of the content. Binary parts are checked against the required properties and the length restrictions of
+// their schema, all other parts are validated against the schema like other request bodies.
+func (router *Router) readUpload(writer http.ResponseWriter, input *openapi3filter.RequestValidationInput,
+	content *openapi3.MediaType) (*Upload, error) {
+	request := input.Request
+	limits := router.uploadLimits
+	if limits.MaxTotalSize > 0 {
+		if request.ContentLength > limits.MaxTotalSize {
+			return nil, &uploadTooLargeError{limit: limits.MaxTotalSize}
+		}
+		request.Body = http.MaxBytesReader(writer, request.Body, limits.MaxTotalSize)
+	}
+	requestBody := input.Route.Operation.RequestBody.Value
+	_, params, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, &openapi3filter.RequestError{Input: input, RequestBody: requestBody, Err: err}
+	}
+	upload := &Upload{Values: make(map[string][]string), Files: make(map[string][]*UploadedFile)}
+	if err := upload.read(multipart.NewReader(request.Body, params["boundary"]), content.Schema.Value,
+		limits); err != nil {
+		_ = upload.RemoveAll()
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, &uploadTooLargeError{limit: limits.MaxTotalSize}
+		}
+		var tooLargeErr *uploadTooLargeError
+		if errors.As(err, &tooLargeErr) {
+			return nil, err
+		}
+		return nil, &openapi3filter.RequestError{Input: input, RequestBody: requestBody,
+			Reason: "failed to decode request body", Err: err}
+	}
+	if len(upload.Values) == 0 && len(upload.Files) == 0 && requestBody.Required {
+		return nil, &openapi3filter.RequestError{Input: input, RequestBody: requestBody,
+			Err: openapi3filter.ErrInvalidRequired}
+	}
+	if err := upload.validate(content.Schema.Value); err != nil {
+		_ = upload.RemoveAll()
+		return nil, &openapi3filter.RequestError{Input: input, RequestBody: requestBody,
+			Reason: "doesn't match schema", Err: err}
+	}
+	return upload, nil
+}
+
+// read reads all parts of the multipart body. Parts are files, if their schema is binary or, for parts without a schema
+// in the request body, if they have a file name. The parts are limited to the MaxPartSize of the UploadLimits or to
+// the maxLength of their schema, if it is lower. Files are kept in memory up to the MaxMemory of the UploadLimits and
+// spooled to temporary files afterwards, while all other parts are limited to 10 MB in total. The number of parts is
+// limited to the MaxParts of the UploadLimits.
+func (upload *Upload) read(reader *multipart.Reader, schema *openapi3.Schema, limits UploadLimits) error {
+	memory := limits.MaxMemory
+	if memory <= 0 {
+		memory = defaultMaxMemory
+	}
+	maxParts := limits.MaxParts
+	if maxParts <= 0 {
+		maxParts = defaultMaxParts
+	}
+	values := int64(maxValuesSize)
+	for parts := 0; ; parts++ {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if parts == maxParts {
+			return &uploadTooLargeError{limit: int64(maxParts), unit: "parts"}
+		}
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+		property := itemSchema(propertySchema(schema.NewRef(), name))
+		if !isFile(property, part.FileName()) {
+			data, err := readLimited(part, name, valueLimit(property, limits.MaxPartSize), &values)
+			if err != nil {
+				return err
+			}
+			upload.Values[name] = append(upload.Values[name], string(data))
+			continue
+		}
+		file, err := spool(part, name, partLimit(property, limits.MaxPartSize), &memory)
+		if err != nil {
+			return err
+		}
+		upload.Files[name] = append(upload.Files[name], file)
+	}
+}
+
+// spool reads the file of the part into memory, as long as the remaining memory is sufficient, and writes it to a
+// temporary file otherwise.
+func spool(part *multipart.Part, name string, limit int64, memory *int64) (*UploadedFile, error) {
+	file := &UploadedFile{Filename: part.FileName(), Header: part.Header}
+	inMemory := *memory
+	if limit > 0 && limit < inMemory {
+		inMemory = limit
+	}
+	var buffer bytes.Buffer
+	size, err := io.CopyN(&buffer, part, inMemory+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if size <= inMemory {
+		*memory -= size
+		file.content, file.Size = buffer.Bytes(), size
+		return file, nil
+	}
+	if limit > 0 && size > limit {
+		return nil, &uploadTooLargeError{part: name, limit: limit}
+	}
+	temp, err := os.CreateTemp("", "openapirouter-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer temp.Close()
+	file.path = temp.Name()
+	var reader io.Reader = io.MultiReader(&buffer, part)
+	if limit > 0 {
+		reader = io.LimitReader(reader, limit+1)
+	}
+	file.Size, err = io.Copy(temp, reader)
+	if err == nil && limit > 0 && file.Size > limit {
+		err = &uploadTooLargeError{part: name, limit: limit}
+	}
+	if err != nil {
+		_ = os.Remove(file.path)
+		return nil, err
+	}
+	return file, nil
+}
+
+// readLimited reads a part, which is not a file, into memory, as long as the remaining memory for values is sufficient.
+func readLimited(part *multipart.Part, name string, limit int64, memory *int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(part, min(limit, *memory)+1))
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(data))
+	if size > limit {
+		return nil, &uploadTooLargeError{part: name, limit: limit}
+	}
+	if size > *memory {
+		return nil, &uploadTooLargeError{limit: maxValuesSize, unit: "bytes of values"}
+	}
+	*memory -= size
+	return data, nil
+}
+
+// validate checks the Upload against the schema of the request body. The binary properties are checked for presence
+// and length, the other properties are validated against their schema.
+func (upload *Upload) validate(schema *openapi3.Schema) error {
+	fieldSchema := *schema
+	fieldSchema.Properties = make(openapi3.Schemas)
+	fieldSchema.Required = nil
+	for name, property := range schema.Properties {
+		if !isBinary(itemSchema(property.Value)) {
+			fieldSchema.Properties[name] = property
+		}
+	}
+	for _, name := range schema.Required {
+		if _, ok := fieldSchema.Properties[name]; ok {
+			fieldSchema.Required = append(fieldSchema.Required, name)
+		} else if len(upload.Files[name]) == 0 {
+			return &openapi3.SchemaError{Value: upload.Files, Schema: schema, SchemaField: "required",
+				Reason: fmt.Sprintf("property %q is missing", name)}
+		}
+	}
+	for name, files := range upload.Files {
+		property := itemSchema(propertySchema(schema.NewRef(), name))
+		if property == nil || property.MinLength == 0 {
+			continue
+		}
+		for _, file := range files {
+			if uint64(file.Size) < property.MinLength {
+				return &openapi3.SchemaError{Value: file.Filename, Schema: property, SchemaField: "minLength",
+					Reason: fmt.Sprintf("file of part %s must be at least %d bytes", name, property.MinLength)}
+			}
+		}
+	}
+	values, err := upload.decodeValues(schema)
+	if err != nil {
+		return err
+	}
+	return fieldSchema.VisitJSON(values, openapi3.MultiErrors())
+}
+
+// decodeValues decodes the values of the Upload according to the schema of their property, so they can be validated
+// and bound like the values of other request bodies.
+func (upload *Upload) decodeValues(schema *openapi3.Schema) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for name, values := range upload.Values {
+		property := propertySchema(schema.NewRef(), name)
+		isArray := property != nil && property.Type == openapi3.TypeArray
+		var decoded []interface{}
+		for _, value := range values {
+			item, err := decodeValue(value, itemSchema(property))
+			if err != nil {
+				return nil, fmt.Errorf("part %s: %w", name, err)
+			}
+			decoded = append(decoded, item)
+		}
+		if isArray {
+			result[name] = decoded
+		} else {
+			result[name] = decoded[0]
+		}
+	}
+	return result, nil
+}
+
+// decodeValue decodes the value of a part according to the schema, which may be nil. Objects are decoded from JSON.
+func decodeValue(value string, schema *openapi3.Schema) (interface{}, error) {
+	if schema != nil && schema.Type == openapi3.TypeObject {
+		var decoded interface{}
+		err := json.Unmarshal([]byte(value), &decoded)
+		return decoded, err
+	}
+	return parsePrimitive(value, schema)
+}
+
+// itemSchema returns the schema of the items of an array schema or the schema itself for any other schema.
+func itemSchema(schema *openapi3.Schema) *openapi3.Schema {
+	if schema != nil && schema.Type == openapi3.TypeArray && schema.Items != nil {
+		return schema.Items.Value
+	}
+	return schema
+}
+
+// isFile reports whether a part with the schema and the file name is a file. The file name is only considered for
+// parts without a typed schema.
+func isFile(schema *openapi3.Schema, filename string) bool {
+	if schema != nil && schema.Type != "" {
+		return isBinary(schema)
+	}
+	return filename != ""
+}
+
+// isBinary reports whether the schema describes a file.
+func isBinary(schema *openapi3.Schema) bool {
+	return schema != nil && schema.Type == openapi3.TypeString && schema.Format == "binary"
+}
+
+// partLimit returns the maximum size of a file with the schema: the maxLength of the schema or the configured
+// maxPartSize, whichever is lower. 0 means no limit.
+func partLimit(schema *openapi3.Schema, maxPartSize int64) int64 {
+	if schema == nil || schema.MaxLength == nil {
+		return maxPartSize
+	}
+	maxLength := int64(*schema.MaxLength)
+	if maxPartSize > 0 && maxPartSize < maxLength {
+		return maxPartSize
+	}
+	return maxLength
+}
+
+// valueLimit returns the maximum size of a part, which is not a file, with the schema. Since UTF-8 encodes a character
+// with up to 4 bytes, a string exceeding 4 bytes per character of its maxLength is too long in any case. The lower of
+// this size and the configured maxPartSize, or 10 MB without maxPartSize, is used.
+func valueLimit(schema *openapi3.Schema, maxPartSize int64) int64 {
+	limit := maxPartSize
+	if limit <= 0 {
+		limit = maxValuesSize
+	}
+	if schema != nil && schema.MaxLength != nil && *schema.MaxLength < uint64(limit)/4 {
+		limit = int64(*schema.MaxLength) * 4
+	}
+	return limit
+}
+
+// withUpload adds the Upload to the context of the request.
+func withUpload(request *http.Request, upload *Upload) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), uploadKey, upload))
+}
+
+// uploadErrorResponse creates the response for an upload, which could not be read. Uploads exceeding the UploadLimits
+// are answered with 413 Request Entity Too Large.
+func (router *Router) uploadErrorResponse(err error) *Response {
+	var tooLargeErr *uploadTooLargeError
+	if errors.As(err, &tooLargeErr) {
+		return router.errMapper.toResponse(NewHTTPError(http.StatusRequestEntityTooLarge, err.Error()))
+	}
+	return router.validationErrorResponse(err)
+}
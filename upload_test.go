@@ -0,0 +1,345 @@
+package openapirouter
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const uploadTestSpec = `
+openapi: 3.0.3
+info:
+  title: Upload-API
+  version: 1.0.0
+paths:
+  /documents:
+    post:
+      operationId: uploadDocument
+      requestBody:
+        required: true
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              required: [title, file]
+              properties:
+                title:
+                  type: string
+                  maxLength: 20
+                version:
+                  type: integer
+                file:
+                  type: string
+                  format: binary
+                  maxLength: 1000
+                attachments:
+                  type: array
+                  items:
+                    type: string
+                    format: binary
+      responses:
+        '204':
+          description: uploaded
+`
+
+type uploadTestPart struct {
+	name     string
+	filename string
+	content  string
+}
+
+func uploadRequest(parts ...uploadTestPart) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		var partWriter io.Writer
+		if part.filename != "" {
+			partWriter, _ = writer.CreateFormFile(part.name, part.filename)
+		} else {
+			partWriter, _ = writer.CreateFormField(part.name)
+		}
+		_, _ = io.WriteString(partWriter, part.content)
+	}
+	_ = writer.Close()
+	request := httptest.NewRequest(http.MethodPost, "/documents", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func getUploadRouter(opts ...Option) (*Router, *int) {
+	router, err := NewRouterFromData([]byte(uploadTestSpec), opts...)
+	if err != nil {
+		panic(err)
+	}
+	calls := 0
+	router.HandleOperation("uploadDocument", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		calls++
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	return router, &calls
+}
+
+func readUploadedFile(t *testing.T, file *UploadedFile) string {
+	reader, err := file.Open()
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+	return string(content)
+}
+
+func TestUpload_ShouldProvideFilesAndValues(t *testing.T) {
+	// given
+	router, _ := getUploadRouter()
+	var upload *Upload
+	var document struct {
+		Title   string `json:"title"`
+		Version int    `json:"version"`
+	}
+	var file, firstAttachment, secondAttachment string
+	router.HandleOperation("uploadDocument", func(request *http.Request, _ map[string]string) (*Response, error) {
+		upload = UploadOf(request)
+		if err := BindBody(request, &document); err != nil {
+			return nil, err
+		}
+		file = readUploadedFile(t, upload.File("file"))
+		firstAttachment = readUploadedFile(t, upload.Files["attachments"][0])
+		secondAttachment = readUploadedFile(t, upload.Files["attachments"][1])
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, uploadRequest(
+		uploadTestPart{name: "title", content: "contract"},
+		uploadTestPart{name: "version", content: "2"},
+		uploadTestPart{name: "file", filename: "contract.pdf", content: "%PDF-1.7"},
+		uploadTestPart{name: "attachments", filename: "a.txt", content: "first"},
+		uploadTestPart{name: "attachments", filename: "b.txt", content: "second"},
+	))
+
+	// then
+	assert.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+	if assert.NotNil(t, upload) {
+		assert.Equal(t, "contract", upload.Value("title"))
+		assert.Equal(t, "contract.pdf", upload.File("file").Filename)
+		assert.Equal(t, int64(8), upload.File("file").Size)
+		assert.Len(t, upload.Files["attachments"], 2)
+	}
+	assert.Equal(t, "contract", document.Title)
+	assert.Equal(t, 2, document.Version)
+	assert.Equal(t, "%PDF-1.7", file)
+	assert.Equal(t, "first", firstAttachment)
+	assert.Equal(t, "second", secondAttachment)
+}
+
+func TestUpload_ShouldClassifyPartsBySchema(t *testing.T) {
+	// given
+	router, _ := getUploadRouter()
+	var upload *Upload
+	router.HandleOperation("uploadDocument", func(request *http.Request, _ map[string]string) (*Response, error) {
+		upload = UploadOf(request)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, uploadRequest(
+		uploadTestPart{name: "title", filename: "title.txt", content: "contract"},
+		uploadTestPart{name: "file", filename: "contract.pdf", content: "%PDF-1.7"},
+		uploadTestPart{name: "signature", filename: "signature.png", content: "PNG"},
+	))
+
+	// then
+	assert.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+	if assert.NotNil(t, upload) {
+		assert.Equal(t, "contract", upload.Value("title"))
+		assert.Nil(t, upload.File("title"))
+		assert.Equal(t, "signature.png", upload.File("signature").Filename)
+	}
+}
+
+func TestUpload_ShouldSpoolLargeFilesToDisk(t *testing.T) {
+	// given
+	router, _ := getUploadRouter(WithUploadLimits(UploadLimits{MaxMemory: 10}))
+	var path, content string
+	router.HandleOperation("uploadDocument", func(request *http.Request, _ map[string]string) (*Response, error) {
+		file := UploadOf(request).File("file")
+		path = file.path
+		content = readUploadedFile(t, file)
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, uploadRequest(
+		uploadTestPart{name: "title", content: "contract"},
+		uploadTestPart{name: "file", filename: "contract.pdf", content: strings.Repeat("x", 100)},
+	))
+
+	// then
+	assert.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+	assert.NotEmpty(t, path)
+	assert.Equal(t, strings.Repeat("x", 100), content)
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUpload_ShouldRejectTooLargeUploads(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   UploadLimits
+		size     int
+		chunked  bool
+		expected string
+	}{
+		{"maxLength", UploadLimits{}, 1001, false, "part file exceeds the limit of 1000 bytes"},
+		{"maxLength spooled", UploadLimits{MaxMemory: 10}, 1001, false, "part file exceeds the limit of 1000 bytes"},
+		{"part size", UploadLimits{MaxPartSize: 50}, 51, false, "part file exceeds the limit of 50 bytes"},
+		{"total size", UploadLimits{MaxTotalSize: 500}, 400, false, "request body exceeds the limit of 500 bytes"},
+		{"total size chunked", UploadLimits{MaxTotalSize: 500}, 400, true,
+			"request body exceeds the limit of 500 bytes"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, calls := getUploadRouter(WithUploadLimits(test.limits))
+			request := uploadRequest(
+				uploadTestPart{name: "title", content: "contract"},
+				uploadTestPart{name: "file", filename: "contract.pdf", content: strings.Repeat("x", test.size)},
+			)
+			if test.chunked {
+				request.ContentLength = -1
+			}
+			recorder := httptest.NewRecorder()
+
+			// when
+			router.ServeHTTP(recorder, request)
+
+			// then
+			assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), test.expected)
+			assert.Equal(t, 0, *calls)
+		})
+	}
+}
+
+func TestUpload_ShouldLimitValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   UploadLimits
+		part     uploadTestPart
+		expected string
+	}{
+		{"maxLength", UploadLimits{}, uploadTestPart{name: "title", content: strings.Repeat("x", 81)},
+			"part title exceeds the limit of 80 bytes"},
+		{"part size", UploadLimits{MaxPartSize: 50}, uploadTestPart{name: "title", content: strings.Repeat("x", 51)},
+			"part title exceeds the limit of 50 bytes"},
+		{"default size", UploadLimits{}, uploadTestPart{name: "comment", content: strings.Repeat("x", 10<<20+1)},
+			"part comment exceeds the limit of 10485760 bytes"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, calls := getUploadRouter(WithUploadLimits(test.limits))
+			recorder := httptest.NewRecorder()
+
+			// when
+			router.ServeHTTP(recorder, uploadRequest(test.part,
+				uploadTestPart{name: "file", filename: "contract.pdf", content: "%PDF-1.7"}))
+
+			// then
+			assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), test.expected)
+			assert.Equal(t, 0, *calls)
+		})
+	}
+}
+
+func TestUpload_ShouldLimitValuesInTotal(t *testing.T) {
+	// given
+	router, calls := getUploadRouter()
+	parts := []uploadTestPart{{name: "title", content: "contract"},
+		{name: "file", filename: "contract.pdf", content: "%PDF-1.7"}}
+	for i := 0; i < 11; i++ {
+		parts = append(parts, uploadTestPart{name: "comment", content: strings.Repeat("x", 1<<20)})
+	}
+	recorder := httptest.NewRecorder()
+
+	// when
+	router.ServeHTTP(recorder, uploadRequest(parts...))
+
+	// then
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "request body exceeds the limit of 10485760 bytes of values")
+	assert.Equal(t, 0, *calls)
+}
+
+func TestUpload_ShouldLimitParts(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   UploadLimits
+		parts    int
+		expected string
+	}{
+		{"default", UploadLimits{}, 1000, "request body exceeds the limit of 1000 parts"},
+		{"max parts", UploadLimits{MaxParts: 5}, 5, "request body exceeds the limit of 5 parts"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, calls := getUploadRouter(WithUploadLimits(test.limits))
+			parts := []uploadTestPart{{name: "title", content: "contract"},
+				{name: "file", filename: "contract.pdf", content: "%PDF-1.7"}}
+			for len(parts) <= test.parts {
+				parts = append(parts, uploadTestPart{name: "comment", content: "x"})
+			}
+			recorder := httptest.NewRecorder()
+
+			// when
+			router.ServeHTTP(recorder, uploadRequest(parts...))
+
+			// then
+			assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), test.expected)
+			assert.Equal(t, 0, *calls)
+		})
+	}
+}
+
+func TestUpload_ShouldValidateUpload(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []uploadTestPart
+	}{
+		{"missing file", []uploadTestPart{{name: "title", content: "contract"}}},
+		{"missing title", []uploadTestPart{{name: "file", filename: "contract.pdf", content: "%PDF-1.7"}}},
+		{"invalid version", []uploadTestPart{{name: "title", content: "contract"}, {name: "version", content: "x"},
+			{name: "file", filename: "contract.pdf", content: "%PDF-1.7"}}},
+		{"title too long", []uploadTestPart{{name: "title", content: strings.Repeat("x", 21)},
+			{name: "file", filename: "contract.pdf", content: "%PDF-1.7"}}},
+		{"empty body", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, calls := getUploadRouter()
+			recorder := httptest.NewRecorder()
+
+			// when
+			router.ServeHTTP(recorder, uploadRequest(test.parts...))
+
+			// then
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Equal(t, 0, *calls)
+		})
+	}
+}
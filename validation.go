@@ -0,0 +1,171 @@
+package openapirouter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"strings"
+)
+
+// ResponseValidationMode defines how the Router treats responses of a HandleRequestFunction which do not match the
+// OpenAPI specification.
+type ResponseValidationMode int
+
+const (
+	// ResponseValidationOff disables the validation of responses. This is the default.
+	ResponseValidationOff ResponseValidationMode = iota
+	// ResponseValidationLog validates the responses and logs any violation, but still writes the invalid response.
+	ResponseValidationLog
+	// ResponseValidationFail validates the responses and replaces invalid responses with an Internal Server Error.
+	ResponseValidationFail
+	// ResponseValidationTest behaves like ResponseValidationFail, if the Router is created with WithTestMode, e.g. by
+	// the setup of tests, and like ResponseValidationOff otherwise.
+	ResponseValidationTest
+)
+
+// enabled returns whether responses have to be buffered and validated at all, depending on whether the Router runs in
+// test mode.
+func (mode ResponseValidationMode) enabled(testMode bool) bool {
+	switch mode {
+	case ResponseValidationLog, ResponseValidationFail:
+		return true
+	case ResponseValidationTest:
+		return testMode
+	default:
+		return false
+	}
+}
+
+// failsOnError returns whether an invalid response must be replaced with an Internal Server Error.
+func (mode ResponseValidationMode) failsOnError() bool {
+	return mode == ResponseValidationFail || mode == ResponseValidationTest
+}
+
+// validateResponse validates the buffered response of a request against the operation of its route. Status codes
+// which are not documented for the operation are treated as an error. Bodies are decoded with the decoders of the
+// Router, including the decoders of structured syntax suffixes like +json. Bodies of media types without a decoder,
+// e.g. files, and streamed bodies are not validated against their schema, only their Content-Type is checked.
+func validateResponse(ctx context.Context, decoders *decoderRegistry,
+	requestInput *openapi3filter.RequestValidationInput, buffer *responseBuffer) error {
+	contentType := buffer.Header().Get("Content-Type")
+	decoder, own := decoders.lookup(contentType)
+	decodable := !buffer.streamed && decoder != nil
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 buffer.statusCode,
+		Header:                 buffer.Header(),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true,
+			ExcludeResponseBody: !decodable || own},
+	}
+	input.SetBodyBytes(buffer.body.Bytes())
+	if err := openapi3filter.ValidateResponse(ctx, input); err != nil || decodable && !own {
+		return err
+	}
+	content := documentedContent(requestInput.Route.Operation, buffer.statusCode)
+	if len(content) == 0 {
+		return nil
+	}
+	mediaType := content.Get(contentType)
+	if mediaType == nil {
+		return &openapi3filter.ResponseError{
+			Input:  input,
+			Reason: fmt.Sprintf("response header Content-Type has unexpected value: %q", contentType),
+		}
+	}
+	if !decodable || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return nil
+	}
+	value, err := decoder(bytes.NewReader(buffer.body.Bytes()), buffer.Header(), mediaType.Schema,
+		func(name string) *openapi3.Encoding { return mediaType.Encoding[name] })
+	if err != nil {
+		return &openapi3filter.ResponseError{Input: input, Reason: "failed to decode response body", Err: err}
+	}
+	if err := mediaType.Schema.Value.VisitJSON(value, openapi3.VisitAsResponse()); err != nil {
+		return &openapi3filter.ResponseError{Input: input, Reason: "response body doesn't match schema", Err: err}
+	}
+	return nil
+}
+
+// ValidationError describes a single violation of the OpenAPI specification found while validating a request. A
+// response for an invalid request contains all violations in the extension member "errors" of the HTTPError.
+type ValidationError struct {
+	// location of the violation: path, query, header, cookie or body
+	In string `json:"in"`
+	// name of the violated parameter, empty for violations of the request body
+	Name string `json:"name,omitempty"`
+	// JSON pointer (RFC 6901) to the invalid value inside the request body or parameter, empty for the whole value
+	Pointer string `json:"pointer,omitempty"`
+	// keyword of the schema that failed, e.g. "required", "enum" or "maxLength"
+	Keyword string `json:"keyword,omitempty"`
+	// human-readable description of the violation
+	Message string `json:"message"`
+}
+
+// requestValidationErrors converts the error returned by openapi3filter.ValidateRequest to a ValidationError for every
+// violation. If multiple errors are reported by MultiError, all of them are converted.
+func requestValidationErrors(err error) []ValidationError {
+	var result []ValidationError
+	switch typedErr := err.(type) {
+	case openapi3.MultiError:
+		for _, e := range typedErr {
+			result = append(result, requestValidationErrors(e)...)
+		}
+	case *openapi3filter.RequestError:
+		location := ValidationError{In: "body"}
+		if typedErr.Parameter != nil {
+			location = ValidationError{In: typedErr.Parameter.In, Name: typedErr.Parameter.Name}
+		}
+		schemaErrors := collectSchemaErrors(typedErr.Err)
+		for _, schemaErr := range schemaErrors {
+			validationErr := location
+			validationErr.Pointer = jsonPointer(schemaErr.JSONPointer())
+			validationErr.Keyword = schemaErr.SchemaField
+			validationErr.Message = schemaErr.Reason
+			if schemaErr.Origin != nil {
+				validationErr.Message = schemaErr.Origin.Error()
+			}
+			result = append(result, validationErr)
+		}
+		if len(schemaErrors) == 0 {
+			validationErr := location
+			validationErr.Message = typedErr.Reason
+			if typedErr.Err != nil {
+				validationErr.Message = typedErr.Err.Error()
+			}
+			if errors.Is(typedErr.Err, openapi3filter.ErrInvalidRequired) {
+				validationErr.Keyword = "required"
+			}
+			result = append(result, validationErr)
+		}
+	}
+	return result
+}
+
+// collectSchemaErrors returns all openapi3.SchemaError contained in the error.
+func collectSchemaErrors(err error) []*openapi3.SchemaError {
+	var result []*openapi3.SchemaError
+	if multiErr, ok := err.(openapi3.MultiError); ok {
+		for _, e := range multiErr {
+			result = append(result, collectSchemaErrors(e)...)
+		}
+		return result
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		result = append(result, schemaErr)
+	}
+	return result
+}
+
+// jsonPointer creates a JSON pointer according to RFC 6901 from the segments of a path.
+func jsonPointer(path []string) string {
+	var builder strings.Builder
+	for _, segment := range path {
+		builder.WriteString("/")
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1"))
+	}
+	return builder.String()
+}
@@ -0,0 +1,338 @@
+package openapirouter
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseValidation_ValidResponse(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.SetResponseValidationMode(ResponseValidationFail)
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       TestData{Data: "test"},
+			Headers:    map[string]string{"X-TEST": "test"},
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "test", res.Header.Get("X-TEST"))
+		var data TestData
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&data))
+		assert.Equal(t, TestData{Data: "test"}, data)
+	}
+}
+
+func TestResponseValidation_FailOnMissingRequiredField(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.SetResponseValidationMode(ResponseValidationFail)
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       InvalidData{Invalid: "test"},
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	}
+}
+
+func TestResponseValidation_FailOnUndocumentedStatusCode(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.SetResponseValidationMode(ResponseValidationFail)
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusCreated,
+			Body:       TestData{Data: "test"},
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	}
+}
+
+func TestResponseValidation_FailOnWrongContentType(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.SetResponseValidationMode(ResponseValidationFail)
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       "test",
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	}
+}
+
+func TestResponseValidation_LogInvalidResponse(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.SetResponseValidationMode(ResponseValidationLog)
+	router.AddRequestHandler("GET", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		return &Response{
+			StatusCode: http.StatusOK,
+			Body:       InvalidData{Invalid: "test"},
+		}, nil
+	})
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		var data InvalidData
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&data))
+		assert.Equal(t, InvalidData{Invalid: "test"}, data)
+	}
+}
+
+func TestResponseValidation_TestModeFailsInTestMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected int
+	}{
+		{"test mode", []Option{WithTestMode()}, http.StatusInternalServerError},
+		{"without test mode", nil, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, server := getRouterAndServerWithOptions("testdata/test-api.yaml",
+				append(test.opts, WithResponseValidation(ResponseValidationTest))...)
+			defer server.Close()
+			router.AddRequestHandler("POST", "/test", func(_ *http.Request, _ map[string]string) (*Response, error) {
+				return &Response{
+					StatusCode: http.StatusOK,
+				}, nil
+			})
+
+			// when
+			res, err := server.Client().Post(server.URL+"/test", "application/json", nil)
+
+			// then
+			assert.Nil(t, err)
+			if assert.NotNil(t, res) {
+				assert.Equal(t, test.expected, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestResponseValidationMode_Enabled(t *testing.T) {
+	assert.False(t, ResponseValidationOff.enabled(true))
+	assert.True(t, ResponseValidationLog.enabled(false))
+	assert.True(t, ResponseValidationFail.enabled(false))
+	assert.True(t, ResponseValidationTest.enabled(true))
+	assert.False(t, ResponseValidationTest.enabled(false))
+}
+
+const validationTestSpec = `
+openapi: 3.0.3
+info:
+  title: Validation-API
+  version: 1.0.0
+paths:
+  /clients/{client}:
+    put:
+      operationId: putClient
+      parameters:
+        - in: path
+          name: client
+          required: true
+          schema:
+            type: integer
+        - in: query
+          name: mode
+          required: true
+          schema:
+            type: string
+            enum: [full, partial]
+        - in: header
+          name: X-Version
+          schema:
+            type: integer
+            minimum: 1
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                tags:
+                  type: array
+                  items:
+                    type: string
+                    maxLength: 3
+      responses:
+        204:
+          description: "Successful"
+`
+
+func TestRequestValidation_ReportsAllViolations(t *testing.T) {
+	// given
+	router, err := NewRouterFromData([]byte(validationTestSpec))
+	if err != nil {
+		panic(err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+	called := false
+	router.HandleOperation("putClient", func(_ *http.Request, _ map[string]string) (*Response, error) {
+		called = true
+		return &Response{StatusCode: http.StatusNoContent}, nil
+	})
+	request, _ := http.NewRequest(http.MethodPut, server.URL+"/clients/42?mode=invalid",
+		strings.NewReader(`{"tags": ["ok", "too long"]}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Version", "0")
+
+	// when
+	res, err := server.Client().Do(request)
+
+	// then
+	assert.Nil(t, err)
+	assert.False(t, called)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		var body struct {
+			Details []string          `json:"details"`
+			Errors  []ValidationError `json:"errors"`
+		}
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Len(t, body.Details, 3)
+		assert.ElementsMatch(t, []ValidationError{
+			{In: "query", Name: "mode", Keyword: "enum", Message: "value is not one of the allowed values [\"full\",\"partial\"]"},
+			{In: "header", Name: "X-Version", Keyword: "minimum", Message: "number must be at least 1"},
+			{In: "body", Pointer: "/name", Keyword: "required", Message: "property \"name\" is missing"},
+			{In: "body", Pointer: "/tags/1", Keyword: "maxLength", Message: "maximum string length is 3"},
+		}, body.Errors)
+	}
+}
+
+func TestRequestValidation_MissingRequiredParameter(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.HandleOperation("getQuery", handleNoContent)
+
+	// when
+	res, err := server.Client().Get(server.URL + "/test/query")
+
+	// then
+	assert.Nil(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		var body struct {
+			Errors []ValidationError `json:"errors"`
+		}
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, []ValidationError{
+			{In: "query", Name: "param", Keyword: "required", Message: "value is required but missing"},
+		}, body.Errors)
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	assert.Equal(t, "", jsonPointer(nil))
+	assert.Equal(t, "/a~1b/m~0n/0", jsonPointer([]string{"a/b", "m~n", "0"}))
+}
+
+const vendorResponseTestSpec = `
+openapi: 3.0.3
+info:
+  title: Vendor-API
+  version: 1.0.0
+paths:
+  /clients:
+    get:
+      operationId: getClients
+      responses:
+        '200':
+          description: clients
+          content:
+            application/vnd.clients+json:
+              schema:
+                type: object
+                required: [data]
+                properties:
+                  data:
+                    type: string
+`
+
+func TestResponseValidation_FailOnInvalidVendorJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       map[string]interface{}
+		statusCode int
+	}{
+		{"valid", map[string]interface{}{"data": "test"}, http.StatusOK},
+		{"missing required field", map[string]interface{}{"other": 1}, http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// given
+			router, err := NewRouterFromData([]byte(vendorResponseTestSpec),
+				WithResponseValidation(ResponseValidationFail))
+			assert.Nil(t, err)
+			router.HandleOperation("getClients", func(_ *http.Request, _ map[string]string) (*Response, error) {
+				return &Response{
+					StatusCode: http.StatusOK,
+					Body:       test.body,
+					Headers:    map[string]string{"Content-Type": "application/vnd.clients+json"},
+				}, nil
+			})
+			recorder := httptest.NewRecorder()
+
+			// when
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/clients", nil))
+
+			// then
+			assert.Equal(t, test.statusCode, recorder.Code, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package openapirouter
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"sort"
+	"strings"
+)
+
+// VerificationError is returned by Router.Verify if the implementation of the OpenAPI specification is incomplete or
+// ambiguous. Operations are identified by their operationId and their method and path, e.g. "getClient (GET
+// /clients/{client})".
+type VerificationError struct {
+	// Unimplemented contains every operation of the specification without a request handler
+	Unimplemented []string
+	// Duplicates contains every operation a request handler was added to more than once
+	Duplicates []string
+	// MissingAuthenticators contains every security scheme referenced by an implemented operation, which has neither
+	// an Authenticator nor an openapi3filter.AuthenticationFunc
+	MissingAuthenticators []string
+}
+
+// implementation of error
+func (er *VerificationError) Error() string {
+	var messages []string
+	if len(er.Unimplemented) > 0 {
+		messages = append(messages, "operations without implementation: "+strings.Join(er.Unimplemented, ", "))
+	}
+	if len(er.Duplicates) > 0 {
+		messages = append(messages, "operations implemented more than once: "+strings.Join(er.Duplicates, ", "))
+	}
+	if len(er.MissingAuthenticators) > 0 {
+		messages = append(messages, "security schemes without authenticator: "+
+			strings.Join(er.MissingAuthenticators, ", "))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Verify checks that a request handler was added for every operation of the OpenAPI specification and that no
+// request handler was added twice for the same operation. Additionally, every security scheme referenced by an
+// implemented operation needs an Authenticator, unless the request handler or the Router has an
+// openapi3filter.AuthenticationFunc. It returns a *VerificationError listing the affected operations and security
+// schemes, so the service can fail fast when it is started or in unit tests.
+func (router *Router) Verify() error {
+	result := &VerificationError{}
+	for _, path := range sortedPaths(router.swagger) {
+		pathItem := router.swagger.Paths[path]
+		for _, method := range sortedMethods(pathItem) {
+			operation := pathItem.GetOperation(method)
+			if _, ok := router.implementations[operation]; !ok {
+				result.Unimplemented = append(result.Unimplemented, describeOperation(method, path, operation))
+			}
+			for _, duplicate := range router.duplicates {
+				if duplicate == operation {
+					result.Duplicates = append(result.Duplicates, describeOperation(method, path, operation))
+					break
+				}
+			}
+		}
+	}
+	result.MissingAuthenticators = router.missingAuthenticators()
+	if len(result.Unimplemented) > 0 || len(result.Duplicates) > 0 || len(result.MissingAuthenticators) > 0 {
+		return result
+	}
+	return nil
+}
+
+// MustBeComplete works like Verify, but panics if the implementation of the OpenAPI specification is incomplete.
+func (router *Router) MustBeComplete() {
+	if err := router.Verify(); err != nil {
+		router.fail(err)
+	}
+}
+
+// sortedPaths returns the paths of the specification in a deterministic order.
+func sortedPaths(swagger *openapi3.T) []string {
+	paths := make([]string, 0, len(swagger.Paths))
+	for path := range swagger.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// sortedMethods returns the methods of all operations of a path in a deterministic order.
+func sortedMethods(pathItem *openapi3.PathItem) []string {
+	operations := pathItem.Operations()
+	methods := make([]string, 0, len(operations))
+	for method := range operations {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// describeOperation returns a human-readable identification of an operation.
+func describeOperation(method string, path string, operation *openapi3.Operation) string {
+	if operation.OperationID == "" {
+		return method + " " + path
+	}
+	return operation.OperationID + " (" + method + " " + path + ")"
+}
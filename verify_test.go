@@ -0,0 +1,147 @@
+package openapirouter
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func handleNoContent(_ *http.Request, _ map[string]string) (*Response, error) {
+	return &Response{StatusCode: http.StatusNoContent}, nil
+}
+
+func TestVerify_Complete(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.HandleOperation("getTestData", handleNoContent)
+	router.HandleOperation("postTestData", handleNoContent)
+	router.HandleOperation("getPathParams", handleNoContent)
+	router.HandleOperation("getQuery", handleNoContent)
+	router.HandleOperationWithAuthFunc("getSecured", handleNoContent, openapi3filter.NoopAuthenticationFunc)
+
+	// when
+	err := router.Verify()
+
+	// then
+	assert.Nil(t, err)
+	assert.NotPanics(t, router.MustBeComplete)
+}
+
+func TestVerify_Unimplemented(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.HandleOperation("getTestData", handleNoContent)
+	router.AddRequestHandler("POST", "/test", handleNoContent)
+	router.HandleOperation("getQuery", handleNoContent)
+
+	// when
+	err := router.Verify()
+
+	// then
+	if assert.IsType(t, &VerificationError{}, err) {
+		verificationErr := err.(*VerificationError)
+		assert.Equal(t, []string{
+			"getPathParams (GET /test/pathParams/{param})",
+			"getSecured (GET /test/secured)",
+		}, verificationErr.Unimplemented)
+		assert.Empty(t, verificationErr.Duplicates)
+	}
+	assert.Panics(t, router.MustBeComplete)
+}
+
+func TestVerify_Duplicates(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.HandleOperation("getTestData", handleNoContent)
+	router.HandleOperation("postTestData", handleNoContent)
+	router.HandleOperation("getPathParams", handleNoContent)
+	router.HandleOperation("getQuery", handleNoContent)
+	router.HandleOperationWithAuthFunc("getSecured", handleNoContent, openapi3filter.NoopAuthenticationFunc)
+	router.AddRequestHandler("GET", "/test", handleNoContent)
+
+	// when
+	err := router.Verify()
+
+	// then
+	if assert.IsType(t, &VerificationError{}, err) {
+		verificationErr := err.(*VerificationError)
+		assert.Empty(t, verificationErr.Unimplemented)
+		assert.Equal(t, []string{"getTestData (GET /test)"}, verificationErr.Duplicates)
+		assert.Equal(t, "operations implemented more than once: getTestData (GET /test)", err.Error())
+	}
+}
+
+func TestVerify_OperationWithoutOperationID(t *testing.T) {
+	// given
+	router, err := NewRouterFromData([]byte(`
+openapi: 3.0.3
+info:
+  title: Test-API
+  version: 1.0.0
+paths:
+  /test:
+    delete:
+      responses:
+        204:
+          description: "Successful"
+`))
+	if err != nil {
+		panic(err)
+	}
+
+	// when
+	err = router.Verify()
+
+	// then
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "operations without implementation: DELETE /test", err.Error())
+	}
+}
+
+func TestVerify_MissingAuthenticators(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.HandleOperation("getTestData", handleNoContent)
+	router.HandleOperation("postTestData", handleNoContent)
+	router.HandleOperation("getPathParams", handleNoContent)
+	router.HandleOperation("getQuery", handleNoContent)
+	router.HandleOperation("getSecured", handleNoContent)
+
+	// when
+	err := router.Verify()
+
+	// then
+	if assert.IsType(t, &VerificationError{}, err) {
+		verificationErr := err.(*VerificationError)
+		assert.Empty(t, verificationErr.Unimplemented)
+		assert.Equal(t, []string{"apiKey"}, verificationErr.MissingAuthenticators)
+		assert.Equal(t, "security schemes without authenticator: apiKey", err.Error())
+	}
+}
+
+func TestVerify_RegisteredAuthenticator(t *testing.T) {
+	// given
+	router, server := getRouterAndServer()
+	defer server.Close()
+	router.HandleOperation("getTestData", handleNoContent)
+	router.HandleOperation("postTestData", handleNoContent)
+	router.HandleOperation("getPathParams", handleNoContent)
+	router.HandleOperation("getQuery", handleNoContent)
+	router.HandleOperation("getSecured", handleNoContent)
+	router.RegisterSecurityScheme("apiKey", func(_ context.Context, _ *openapi3filter.AuthenticationInput) (
+		interface{}, error) {
+		return nil, nil
+	})
+
+	// when
+	err := router.Verify()
+
+	// then
+	assert.Nil(t, err)
+}